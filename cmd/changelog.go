@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/diffing"
+	"github.com/spf13/cobra"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generate Markdown changelog of qapi document between git revisions",
+	Long: `Compiles the input at two git revisions of its local repository and writes
+Markdown changelog of their differences, grouped by tag and operation. Without
+--to the working tree is compared. Files the input imports are taken from the
+same revision.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		output, _ := cmd.Flags().GetString("output")
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+
+		if res := ChangelogFile(output, input, from, to); res != 0 {
+			os.Exit(res)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+
+	changelogCmd.Flags().String("from", "", "Git revision of the old document, e.g. v1.0.0")
+	changelogCmd.MarkFlagRequired("from")
+	changelogCmd.Flags().String("to", "", "Git revision of the new document, the working tree when empty")
+	changelogCmd.Flags().StringP("output", "o", "", "Output filepath, standard output when empty")
+	changelogCmd.MarkFlagFilename("output", "md")
+}
+
+// git runs git in dir, its error output is returned as error
+func git(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %v: %v", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %v: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// extractRevision writes tree of revision of repository at root to dir
+func extractRevision(root, revision, dir string) error {
+	archive, err := git(root, "archive", "--format=tar", revision)
+	if err != nil {
+		return err
+	}
+
+	reader := tar.NewReader(bytes.NewReader(archive))
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// ChangelogFile writes changelog of input between git revisions from and to
+// to output, empty to is the working tree
+func ChangelogFile(output, input, from, to string) int {
+	abs, err := filepath.Abs(input)
+	if err != nil {
+		errorLogger.Print(err)
+		return 1
+	}
+
+	top, err := git(filepath.Dir(abs), "rev-parse", "--show-toplevel")
+	if err != nil {
+		errorLogger.Printf("Unable to find git repository of %v: %v", input, err)
+		return 1
+	}
+	root := strings.TrimSpace(string(top))
+
+	// git reports root with symlinks resolved, e.g. of temporary directories
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		abs = filepath.Join(dir, filepath.Base(abs))
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		errorLogger.Printf("%v is outside of its git repository %v", input, root)
+		return 1
+	}
+
+	tmp, err := os.MkdirTemp("", "qapi-changelog-")
+	if err != nil {
+		errorLogger.Print(err)
+		return 1
+	}
+	defer os.RemoveAll(tmp)
+
+	// revisionInput returns path of the input at revision, the working tree
+	// one for empty revision
+	revisionInput := func(revision string) (string, error) {
+		if revision == "" {
+			return abs, nil
+		}
+
+		log.Printf("Reading revision %v", revision)
+
+		dir, err := os.MkdirTemp(tmp, "rev-")
+		if err != nil {
+			return "", err
+		}
+		if err := extractRevision(root, revision, dir); err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, rel), nil
+	}
+
+	oldInput, err := revisionInput(from)
+	if err != nil {
+		errorLogger.Printf("Unable to read revision %v: %v", from, err)
+		return 1
+	}
+
+	newInput, err := revisionInput(to)
+	if err != nil {
+		errorLogger.Printf("Unable to read revision %v: %v", to, err)
+		return 1
+	}
+
+	oldDocument, res := compileDocument(oldInput)
+	if res != 0 {
+		return res
+	}
+
+	newDocument, res := compileDocument(newInput)
+	if res != 0 {
+		return res
+	}
+
+	changes, err := diffing.Compare(oldDocument, newDocument)
+	if err != nil {
+		errorLogger.Printf("Unable to compare documents: %v", err)
+		return 5
+	}
+
+	title := fmt.Sprintf("Changelog %v...%v", from, to)
+	if to == "" {
+		title = fmt.Sprintf("Changelog since %v", from)
+	}
+
+	var buf bytes.Buffer
+	if err := diffing.WriteChangelog(&buf, title, changes); err != nil {
+		errorLogger.Printf("Unable to write changelog: %v", err)
+		return 4
+	}
+
+	if output == "" {
+		os.Stdout.Write(buf.Bytes())
+		return 0
+	}
+
+	log.Printf("Writing to %v", output)
+
+	if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		errorLogger.Printf("Unable to write file %v: %v", output, err)
+		return 4
+	}
+
+	return 0
+}
@@ -4,14 +4,14 @@ Copyright © 2026 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
 
-	"github.com/goccy/go-yaml"
 	"github.com/masnyjimmy/qapi/compilation"
 	"github.com/masnyjimmy/qapi/docs"
-	"github.com/masnyjimmy/qapi/validation"
+	"github.com/masnyjimmy/qapi/loader"
 	"github.com/spf13/cobra"
 )
 
@@ -29,8 +29,54 @@ to quickly create a Cobra application.`,
 
 		output, _ := cmd.Flags().GetString("output")
 		input, _ := cmd.Flags().GetString("input")
+		orderName, _ := cmd.Flags().GetString("order")
+		targetName, _ := cmd.Flags().GetString("target")
+		externalRefsName, _ := cmd.Flags().GetString("external-refs")
+		prune, _ := cmd.Flags().GetBool("prune")
+		generateIds, _ := cmd.Flags().GetBool("generate-ids")
+		idTemplate, _ := cmd.Flags().GetString("id-template")
+		idCasingName, _ := cmd.Flags().GetString("id-casing")
+
+		order, err := compilation.ParseOrder(orderName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		target, err := compilation.ParseTarget(targetName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		externalRefs, err := compilation.ParseExternalRefs(externalRefsName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		idCasing, err := compilation.ParseCasing(idCasingName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
 
-		if res := CompileFile(output, input); res != 0 {
+		if err := compilation.CheckIdTemplate(idTemplate); err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		opts := compilation.Options{
+			Target:       target,
+			Order:        order,
+			ExternalRefs: externalRefs,
+			Prune:        prune,
+			GenerateIds:  generateIds,
+			IdTemplate:   idTemplate,
+			IdCasing:     idCasing,
+		}
+
+		if res := CompileFile(output, input, opts); res != 0 {
 			os.Exit(res)
 		}
 	},
@@ -54,33 +100,53 @@ func init() {
 	compileCmd.MarkFlagRequired("output")
 	compileCmd.MarkFlagFilename("output", "yaml", "json")
 
+	compileCmd.Flags().String("order", "sorted", "Order of paths, schemas and responses: sorted or source")
+	compileCmd.Flags().String("target", string(compilation.TargetOpenAPI31), "Output format: openapi3.1, openapi3.0 or swagger2")
+	compileCmd.Flags().String("external-refs", string(compilation.ExternalBundle), "Schemas of external files: bundle to components or ref them")
+	compileCmd.Flags().Bool("prune", false, "Drop schemas no operation, webhook or default response references")
+	compileCmd.Flags().Bool("generate-ids", false, "Generate ids of operations without one from their method and path")
+	compileCmd.Flags().String("id-template", compilation.DefaultIdTemplate, "Template of generated ids, {method} and {path} are replaced by their words")
+	compileCmd.Flags().String("id-casing", string(compilation.CasingCamel), "Casing of generated ids: camel, pascal, snake or kebab")
+
 }
 
-func CompileFile(output, input string) int {
+// loadDocument reads, validates and parses input with its imports, non zero
+// exit code is returned on failure
+func loadDocument(input string) (*docs.Document, *docs.Source, int) {
 
 	log.Printf("Reading %v", input)
 
-	docBytes, err := os.ReadFile(input)
+	project, err := loader.Load(input)
 
-	if err != nil {
+	var diagnostics docs.Diagnostics
+
+	switch {
+	case errors.As(err, &diagnostics):
+		printDiagnostics(nil, diagnostics)
+		errorLogger.Print("Validation failed")
+		return nil, nil, 2
+	case errors.Is(err, loader.ErrParse):
+		errorLogger.Print(err)
+		return nil, nil, 3
+	case err != nil:
 		errorLogger.Printf("Unable to read file \"%v\": %v", input, err)
-		return 1
+		return nil, nil, 1
 	}
 
-	log.Print("Validating schema..")
-
-	if err := validation.Validate(docBytes); err != nil {
-		errorLogger.Printf("Validation failed: %v", err)
-		return 2
+	if len(project.Files) > 1 {
+		log.Printf("Imported %v files", len(project.Files)-1)
 	}
 
-	log.Printf("Parsing document..")
+	return project.Document, project.Source, 0
+}
 
-	var document docs.Document
+// CompileFile compiles input to output, opts.Source and opts.BaseDir are set
+// to the input, opts.OutputDir to directory of the output
+func CompileFile(output, input string, opts compilation.Options) int {
 
-	if err := yaml.Unmarshal(docBytes, &document); err != nil {
-		errorLogger.Printf("Unable to parse document: %v", err)
-		return 3
+	document, source, res := loadDocument(input)
+	if res != 0 {
+		return res
 	}
 
 	log.Print("Compiling api document..")
@@ -89,16 +155,33 @@ func CompileFile(output, input string) int {
 
 	log.Printf("Output file extension: %v", ext)
 
+	var (
+		docBytes    []byte
+		diagnostics docs.Diagnostics
+		err         error
+	)
+
+	opts.Source = source
+	opts.BaseDir = filepath.Dir(input)
+	opts.OutputDir = filepath.Dir(output)
+
 	switch ext {
 	case ".json":
 		log.Printf("Type selected: json")
-		docBytes, err = compilation.CompileToJSON(&document)
+		docBytes, diagnostics, err = compilation.CompileToJSON(document, opts)
 	case ".yaml":
 		log.Printf("Type selected: yaml")
-		docBytes, err = compilation.CompileToYAML(&document)
+		docBytes, diagnostics, err = compilation.CompileToYAML(document, opts)
 	default:
 		log.Printf("Unkown file extension, selecting yaml")
-		docBytes, err = compilation.CompileToYAML(&document)
+		docBytes, diagnostics, err = compilation.CompileToYAML(document, opts)
+	}
+
+	printDiagnostics(source, diagnostics)
+
+	if err != nil {
+		errorLogger.Print("Compilation failed")
+		return 5
 	}
 
 	log.Printf("Writing to %v", output)
@@ -111,3 +194,16 @@ func CompileFile(output, input string) int {
 	log.Printf("Finished succesfully :)")
 	return 0
 }
+
+func printDiagnostics(source *docs.Source, diagnostics docs.Diagnostics) {
+	source.LocateAll(diagnostics)
+	diagnostics.Sort()
+
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == docs.SeverityError {
+			errorLogger.Print(diagnostic)
+		} else {
+			log.Print(diagnostic)
+		}
+	}
+}
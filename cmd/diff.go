@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/diffing"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old.qapi.yaml> <new.qapi.yaml>",
+	Short: "Compare two versions of qapi document",
+	Long: `Compiles both documents and lists changes of their operations, classified
+as breaking or non-breaking. Non zero exit code means there are breaking
+changes. The old document may also be given by --input.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		output, _ := cmd.Flags().GetString("output")
+		formatName, _ := cmd.Flags().GetString("format")
+
+		format, err := diffing.ParseFormat(formatName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		if input != "" {
+			args = append([]string{input}, args...)
+		}
+		if len(args) != 2 {
+			errorLogger.Print("diff requires old and new document")
+			os.Exit(1)
+		}
+
+		if res := DiffFiles(output, args[0], args[1], format); res != 0 {
+			os.Exit(res)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	// documents are arguments, the input flag shadows required one of root
+	diffCmd.Flags().StringP("input", "i", "", "Old qapi document, instead of the first argument")
+	diffCmd.Flags().StringP("output", "o", "", "Output filepath, standard output when empty")
+	diffCmd.Flags().String("format", string(diffing.FormatText), "Output format: text, markdown or json")
+}
+
+// compileDocument loads and compiles input, non zero exit code is returned
+// on failure
+func compileDocument(input string) (*compilation.Document, int) {
+	document, source, res := loadDocument(input)
+	if res != 0 {
+		return nil, res
+	}
+
+	var out compilation.Document
+	diagnostics, err := compilation.Compile(&out, document, compilation.Options{
+		Source:  source,
+		BaseDir: filepath.Dir(input),
+	})
+	if err != nil {
+		printDiagnostics(source, diagnostics)
+		errorLogger.Printf("Compilation of %v failed", input)
+		return nil, 5
+	}
+
+	return &out, 0
+}
+
+// DiffFiles writes changes from old to new document to output, exit code
+// is 1 when there are breaking changes
+func DiffFiles(output, old, new string, format diffing.Format) int {
+	oldDocument, res := compileDocument(old)
+	if res != 0 {
+		return res
+	}
+
+	newDocument, res := compileDocument(new)
+	if res != 0 {
+		return res
+	}
+
+	changes, err := diffing.Compare(oldDocument, newDocument)
+	if err != nil {
+		errorLogger.Printf("Unable to compare documents: %v", err)
+		return 5
+	}
+
+	var buf bytes.Buffer
+	if err := diffing.Write(&buf, changes, format); err != nil {
+		errorLogger.Printf("Unable to write changes: %v", err)
+		return 4
+	}
+
+	if output == "" {
+		os.Stdout.Write(buf.Bytes())
+	} else {
+		log.Printf("Writing to %v", output)
+
+		if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+			errorLogger.Printf("Unable to write file %v: %v", output, err)
+			return 4
+		}
+	}
+
+	if diffing.HasBreaking(changes) {
+		errorLogger.Print("Breaking changes found")
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"log"
+	"os"
+
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/formatting"
+	"github.com/spf13/cobra"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Rewrite qapi document in canonical layout",
+	Long: `Rewrites qapi document in canonical layout, comments are kept. Keys of
+known sections get canonical order, schema expressions lose whitespace and
+scalars are quoted only when they have to. With --check the file is not
+written, non zero exit code means it isn't formatted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		check, _ := cmd.Flags().GetBool("check")
+		orderName, _ := cmd.Flags().GetString("order")
+
+		order, err := compilation.ParseOrder(orderName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		if res := FormatFile(input, check, formatting.Options{Order: order}); res != 0 {
+			os.Exit(res)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+
+	fmtCmd.Flags().Bool("check", false, "Only check the file is formatted, exit with 1 when it isn't")
+	fmtCmd.Flags().String("order", "sorted", "Order of schemas: sorted or source")
+}
+
+// FormatFile formats input in place, with check it's only compared
+func FormatFile(input string, check bool, opts formatting.Options) int {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		errorLogger.Printf("Unable to read file \"%v\": %v", input, err)
+		return 1
+	}
+
+	formatted, err := formatting.Format(data, opts)
+	if err != nil {
+		errorLogger.Printf("Unable to parse file %v: %v", input, err)
+		return 3
+	}
+
+	if bytes.Equal(data, formatted) {
+		return 0
+	}
+
+	if check {
+		errorLogger.Printf("%v is not formatted", input)
+		return 1
+	}
+
+	log.Printf("Writing to %v", input)
+
+	if err := os.WriteFile(input, formatted, 0644); err != nil {
+		errorLogger.Printf("Unable to write file %v: %v", input, err)
+		return 4
+	}
+
+	return 0
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/masnyjimmy/qapi/decompilation"
+	"github.com/masnyjimmy/qapi/docs"
+	"github.com/masnyjimmy/qapi/validation"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Convert OpenAPI 3.0 or 3.1 document to qapi document",
+	Long: `Converts OpenAPI 3.0 or 3.1 document to qapi document. Flat paths are
+nested, schemas become schema expressions, error responses most operations
+share become defaultResponses and parameters repeated by operations become
+traits. Everything qapi can't express is reported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		output, _ := cmd.Flags().GetString("output")
+
+		if res := ImportFile(output, input); res != 0 {
+			os.Exit(res)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringP("output", "o", "api.qapi.yaml", "Output filepath")
+	importCmd.MarkFlagRequired("output")
+	importCmd.MarkFlagFilename("output", "yaml")
+}
+
+// ImportFile decompiles OpenAPI document of input to qapi document of output
+func ImportFile(output, input string) int {
+	log.Printf("Reading %v", input)
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		errorLogger.Printf("Unable to read file \"%v\": %v", input, err)
+		return 1
+	}
+
+	log.Print("Importing OpenAPI document..")
+
+	bytes, diagnostics, err := decompilation.Decompile(data)
+	if err != nil {
+		errorLogger.Print(err)
+		return 3
+	}
+
+	source, _ := docs.ParseSource(input, data)
+	printDiagnostics(source, diagnostics)
+
+	log.Printf("Writing to %v", output)
+
+	if err := os.WriteFile(output, bytes, 0644); err != nil {
+		errorLogger.Printf("Unable to write file %v: %v", output, err)
+		return 4
+	}
+
+	if err := validation.Validate(bytes); err != nil {
+		source, _ := docs.ParseSource(output, bytes)
+		printDiagnostics(source, docs.AsDiagnostics(err))
+		errorLogger.Print("Imported document is invalid")
+		return 5
+	}
+
+	log.Printf("Finished succesfully :)")
+	return 0
+}
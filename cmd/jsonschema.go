@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/spf13/cobra"
+)
+
+var jsonSchemaCmd = &cobra.Command{
+	Use:   "jsonschema",
+	Short: "Export schemas as JSON Schema 2020-12 documents",
+	Long: `Exports every schema of the document as a self contained JSON Schema
+2020-12 document <output>/<Name>.schema.json, schemas it references are put
+to its $defs. With --bundle all schemas are written to a single document
+with $defs only.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		output, _ := cmd.Flags().GetString("output")
+		bundle, _ := cmd.Flags().GetBool("bundle")
+		orderName, _ := cmd.Flags().GetString("order")
+		externalRefsName, _ := cmd.Flags().GetString("external-refs")
+
+		order, err := compilation.ParseOrder(orderName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		externalRefs, err := compilation.ParseExternalRefs(externalRefsName)
+		if err != nil {
+			errorLogger.Print(err)
+			os.Exit(1)
+		}
+
+		opts := compilation.Options{
+			Order:        order,
+			ExternalRefs: externalRefs,
+		}
+
+		if res := ExportJSONSchemas(output, input, bundle, opts); res != 0 {
+			os.Exit(res)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jsonSchemaCmd)
+
+	jsonSchemaCmd.Flags().StringP("output", "o", "schemas", "Output directory, or output file with --bundle")
+	jsonSchemaCmd.Flags().Bool("bundle", false, "Write all schemas to single document")
+	jsonSchemaCmd.Flags().String("order", "sorted", "Order of $defs: sorted or source")
+	jsonSchemaCmd.Flags().String("external-refs", string(compilation.ExternalBundle), "Schemas of external files: bundle to $defs or ref them")
+}
+
+// ExportJSONSchemas writes schemas of input as JSON Schema documents,
+// opts.Source and opts.BaseDir are set to the input, opts.OutputDir to the
+// output directory, or directory of the output file with bundle
+func ExportJSONSchemas(output, input string, bundle bool, opts compilation.Options) int {
+	document, source, res := loadDocument(input)
+	if res != 0 {
+		return res
+	}
+
+	opts.Source = source
+	opts.BaseDir = filepath.Dir(input)
+	opts.OutputDir = output
+	if bundle {
+		opts.OutputDir = filepath.Dir(output)
+	}
+
+	log.Print("Exporting schemas..")
+
+	if bundle {
+		bytes, diagnostics, err := compilation.ExportJSONSchemaBundle(document, opts)
+
+		printDiagnostics(source, diagnostics)
+
+		if err != nil {
+			errorLogger.Print("Export failed")
+			return 5
+		}
+
+		log.Printf("Writing to %v", output)
+
+		if err := os.WriteFile(output, bytes, 0644); err != nil {
+			errorLogger.Printf("Unable to write file %v: %v", output, err)
+			return 4
+		}
+
+		log.Printf("Finished succesfully :)")
+		return 0
+	}
+
+	schemas, diagnostics, err := compilation.ExportJSONSchemas(document, opts)
+
+	printDiagnostics(source, diagnostics)
+
+	if err != nil {
+		errorLogger.Print("Export failed")
+		return 5
+	}
+
+	if err := os.MkdirAll(output, 0755); err != nil {
+		errorLogger.Printf("Unable to create directory %v: %v", output, err)
+		return 4
+	}
+
+	for name, bytes := range schemas {
+		path := filepath.Join(output, name+".schema.json")
+
+		log.Printf("Writing to %v", path)
+
+		if err := os.WriteFile(path, bytes, 0644); err != nil {
+			errorLogger.Printf("Unable to write file %v: %v", path, err)
+			return 4
+		}
+	}
+
+	log.Printf("Finished succesfully :)")
+	return 0
+}
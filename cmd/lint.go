@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/masnyjimmy/qapi/lint"
+	"github.com/spf13/cobra"
+)
+
+// defaultLintConfig is looked up next to the input when --config isn't set
+const defaultLintConfig = ".qapi-lint.yaml"
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check qapi document against API style rules",
+	Long: `Checks qapi document against API style rules, e.g. snake_case operation
+ids, tagged operations or plural collection names. Severity of rules is set
+in config file, by default .qapi-lint.yaml next to the input. Findings on a
+node and nodes under it are disabled by "# qapi-lint-disable" comment,
+optionally followed by names of rules.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		input, _ := cmd.Flags().GetString("input")
+		config, _ := cmd.Flags().GetString("config")
+
+		if res := LintFile(input, config); res != 0 {
+			os.Exit(res)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().String("config", "", "Lint config file (default .qapi-lint.yaml next to the input)")
+	lintCmd.MarkFlagFilename("config", "yaml")
+}
+
+// LintFile lints input with rules configured by config file, empty config
+// uses the default one when it exists
+func LintFile(input, config string) int {
+	opts := lint.Options{BaseDir: filepath.Dir(input)}
+
+	if config == "" {
+		if _, err := os.Stat(filepath.Join(opts.BaseDir, defaultLintConfig)); !errors.Is(err, fs.ErrNotExist) {
+			config = filepath.Join(opts.BaseDir, defaultLintConfig)
+		}
+	}
+
+	if config != "" {
+		log.Printf("Using lint config %v", config)
+
+		loaded, err := lint.LoadConfig(config)
+		if err != nil {
+			errorLogger.Print(err)
+			return 1
+		}
+		opts.Config = loaded
+	}
+
+	document, source, res := loadDocument(input)
+	if res != 0 {
+		return res
+	}
+
+	opts.Source = source
+
+	diagnostics, err := lint.Lint(document, opts)
+	printDiagnostics(source, diagnostics)
+
+	if err != nil {
+		errorLogger.Print("Compilation failed")
+		return 5
+	}
+
+	if diagnostics.HasErrors() {
+		errorLogger.Print("Lint failed")
+		return 6
+	}
+
+	log.Printf("Found %v problems", len(diagnostics))
+	return 0
+}
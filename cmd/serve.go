@@ -5,16 +5,16 @@ package cmd
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"path/filepath"
 
-	"github.com/goccy/go-yaml"
 	"github.com/masnyjimmy/qapi/compilation"
 	"github.com/masnyjimmy/qapi/docs"
+	"github.com/masnyjimmy/qapi/loader"
 	"github.com/masnyjimmy/qapi/swagger"
-	"github.com/masnyjimmy/qapi/validation"
 	"github.com/rs/cors"
 	"github.com/spf13/cobra"
 )
@@ -60,36 +60,46 @@ func init() {
 		5. marshal
 */
 
-func readAPI(filename string) ([]byte, error) {
+// readAPI compiles filename, files it consists of are returned to be watched
+func readAPI(filename string) ([]byte, []string, error) {
 
-	docBytes, err := os.ReadFile(filename)
+	project, err := loader.Load(filename)
 
-	if err != nil {
-		return nil, fmt.Errorf("Unable to read file: %w", err)
-	}
+	var diagnostics docs.Diagnostics
 
-	if err := validation.Validate(docBytes); err != nil {
-		return nil, fmt.Errorf("Validation error: %w", err)
+	switch {
+	case errors.As(err, &diagnostics):
+		return nil, nil, fmt.Errorf("Validation error:\n%w", diagnostics)
+	case errors.Is(err, loader.ErrParse):
+		panic(err) // this shouln't happen
+	case err != nil:
+		return nil, nil, fmt.Errorf("Unable to read file: %w", err)
 	}
 
-	var document docs.Document
+	source := project.Source
 
-	if err := yaml.Unmarshal(docBytes, &document); err != nil {
-		panic(err) // this shouln't happen
-	}
+	docBytes, diagnostics, err := compilation.CompileToJSON(project.Document, compilation.Options{
+		BaseDir: filepath.Dir(filename),
+	})
 
-	docBytes, err = compilation.CompileToJSON(&document)
+	source.LocateAll(diagnostics)
+	diagnostics.Sort()
 
 	if err != nil {
-		return nil, fmt.Errorf("Compilation error: %w", err)
+		// err holds diagnostics from before they were located
+		return nil, project.Files, fmt.Errorf("Compilation error:\n%w", diagnostics.Err())
 	}
 
-	return docBytes, nil
+	for _, diagnostic := range diagnostics {
+		log.Print(diagnostic)
+	}
+
+	return docBytes, project.Files, nil
 }
 
 func Serve(input string) {
 
-	document, err := readAPI(input)
+	document, files, err := readAPI(input)
 
 	if err != nil {
 		log.Fatal(err)
@@ -109,6 +119,15 @@ func Serve(input string) {
 	if err != nil {
 		log.Printf("Unable to watch for file updates: %v", err)
 	} else {
+		watchImports := func(files []string) {
+			for _, file := range files {
+				if err := watcher.Add(file); err != nil {
+					log.Printf("Unable to watch for updates of %v: %v", file, err)
+				}
+			}
+		}
+		watchImports(files)
+
 		watchHandler := func() {
 			for err := range watcher.Update {
 				if err != nil {
@@ -116,7 +135,9 @@ func Serve(input string) {
 					continue
 				}
 
-				bytes, err := readAPI(input)
+				bytes, files, err := readAPI(input)
+				watchImports(files)
+
 				if err != nil {
 					log.Printf("Document update failed: %v", err)
 					continue
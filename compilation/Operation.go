@@ -1,10 +1,27 @@
 package compilation
 
+import "github.com/masnyjimmy/qapi/docs"
+
 type Operation struct {
-	Summary     string                  `json:"summary,omitempty" yaml:"summary,omitempty"`
-	OperationId string                  `json:"operationId,omitempty" yaml:"operationId,omitempty"`
-	Tags        []string                `json:"tags,omitempty" yaml:"tags,omitempty"`
-	Parameters  []Parameter             `json:"parameters,omitempty" yaml:"parameters,omitempty"`
-	RequestBody *RequestBody            `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
-	Responses   map[StatusCode]Response `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Summary     string                `json:"summary,omitempty" yaml:"summary,omitempty"`
+	OperationId string                `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Tags        []string              `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   Responses             `json:"responses,omitempty" yaml:"responses,omitempty"`
+	Callbacks   map[string]Callback   `json:"callbacks,omitempty" yaml:"callbacks,omitempty"`
+	Security    []SecurityRequirement `json:"security,omitempty" yaml:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+
+	// node of qapi method, used to locate diagnostics
+	at docs.NodePath
+	// OperationId was generated, see Options.GenerateIds
+	generatedId bool
+}
+
+func (o Operation) MarshalJSON() ([]byte, error) {
+	type operation Operation
+	return marshalExtendedJSON(operation(o), o.Extensions)
 }
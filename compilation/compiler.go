@@ -1,12 +1,14 @@
 package compilation
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"path"
-	"reflect"
-	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/goccy/go-yaml"
@@ -17,77 +19,139 @@ type CompileContext struct {
 	in  *docs.Document
 	out *Document
 
-	defaultResponses map[StatusCode]Response
+	// set only for OrderSource, positions of compiled nodes are kept then
+	source *docs.Source
+	// source of document in any order, nil when not given, tells which of
+	// operations sharing id is declared first
+	declared *docs.Source
+
+	defaultResponses map[string]map[StatusCode]Response
 	compiledTraits   map[string]PrecompiledTrait
-}
 
-type PrecompiledTrait struct {
-	args   []string
-	target docs.Trait
+	operations map[string]*Operation
+	links      []pendingLink
+
+	// names of traits which definitions failed to compile
+	failedTraits map[string]bool
+	// names of traits applied to any method, directly or by other traits
+	usedTraits map[string]bool
+	// drop component schemas which aren't referenced
+	prune bool
+
+	// generate ids of operations without one
+	generateIds bool
+	idTemplate  string
+	idCasing    Casing
+
+	externalRefs ExternalRefs
+	baseDir      string
+	outputDir    string
+	// loaded external files by path, nil when unreadable
+	externals map[string]any
+	// names of bundled external schemas by file#pointer
+	bundled map[string]string
+
+	diagnostics docs.Diagnostics
 }
 
-func (p *PrecompiledTrait) compileSchema(schema docs.Schema, r *strings.Replacer) docs.Schema {
-	switch t := schema.Value.(type) {
-	case string:
-		return docs.Schema{
-			Value: r.Replace(t),
-		}
-	case docs.Properties:
+// errReported marks errors already recorded in diagnostics, e.g. uses of
+// failed trait definitions, so they are not reported again
+var errReported = errors.New("error already reported")
 
-		for idx, prop := range t {
-			t[idx].Schema = p.compileSchema(prop.Schema, r)
-		}
+// pendingLink is validated after all operations are compiled
+type pendingLink struct {
+	name string
+	// path of response declaring link
+	at   docs.NodePath
+	link docs.Link
+}
 
-		return docs.Schema{
-			Value: t,
-		}
-	default:
-		panic(fmt.Errorf("invalid schema underlying type: %v", reflect.TypeOf(schema).Name()))
+func MapArray[T ~[]I, U ~[]O, I any, O any](in T, out *U, mapFn func(idx int, in I) O) {
+	(*out) = make(U, len(in))
+
+	for idx, val := range in {
+		(*out)[idx] = mapFn(idx, val)
 	}
 }
 
-func (p PrecompiledTrait) Compile(values []string) (docs.Trait, error) {
-	if len(p.args) != len(values) {
-		return docs.Trait{}, fmt.Errorf("Invalid number of values: %v (expected: %v)", len(values), len(p.args))
+func newCompileContext(input *docs.Document, output *Document, opts Options) (*CompileContext, error) {
+	c := &CompileContext{
+		in:           input,
+		out:          output,
+		operations:   make(map[string]*Operation),
+		failedTraits: make(map[string]bool),
+		usedTraits:   make(map[string]bool),
+		prune:        opts.Prune,
+		generateIds:  opts.GenerateIds,
+		idTemplate:   cmp.Or(opts.IdTemplate, DefaultIdTemplate),
+		idCasing:     cmp.Or(opts.IdCasing, CasingCamel),
+		externalRefs: cmp.Or(opts.ExternalRefs, ExternalBundle),
+		baseDir:      opts.BaseDir,
+		outputDir:    cmp.Or(opts.OutputDir, opts.BaseDir),
+		declared:     opts.Source,
+		externals:    make(map[string]any),
+		bundled:      make(map[string]string),
+	}
+
+	if opts.Order == OrderSource {
+		if opts.Source == nil {
+			return nil, fmt.Errorf("source order requires document source")
+		}
+		c.source = opts.Source
 	}
 
-	var oldnew []string = make([]string, 0)
-
-	for idx, arg := range p.args {
-		oldnew = append(oldnew, "#"+arg, values[idx])
+	if c.generateIds {
+		if err := CheckIdTemplate(c.idTemplate); err != nil {
+			return nil, err
+		}
 	}
 
-	replacer := strings.NewReplacer(oldnew...)
+	return c, nil
+}
 
-	for idx, params := range p.target.Params {
-		p.target.Params[idx].Schema = p.compileSchema(params.Schema, replacer)
+// position of node used to order output, invalid when not ordered by source
+func (c *CompileContext) position(at docs.NodePath) nodePos {
+	if c.source == nil {
+		return nodePos{}
 	}
 
-	for idx, headers := range p.target.Headers {
-		p.target.Headers[idx].Schema = p.compileSchema(headers.Schema, replacer)
+	pos := c.source.Position(at)
+	if !pos.IsValid() {
+		return nodePos{}
+	}
+	return nodePos{
+		file:   slices.Index(c.source.Files(), pos.File),
+		line:   pos.Line,
+		column: pos.Column,
 	}
-
-	return p.target, nil
 }
 
-func MapArray[T ~[]I, U ~[]O, I any, O any](in T, out *U, mapFn func(idx int, in I) O) {
-	(*out) = make(U, len(in))
+// warn records warning in diagnostics
+func (c *CompileContext) warn(at docs.NodePath, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, docs.Diagnostic{
+		Severity: docs.SeverityWarning,
+		Path:     at,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
 
-	for idx, val := range in {
-		(*out)[idx] = mapFn(idx, val)
+// report records error in diagnostics and lets compilation continue
+func (c *CompileContext) report(err error) {
+	if err == nil || errors.Is(err, errReported) {
+		return
 	}
+	c.diagnostics = append(c.diagnostics, docs.NewDiagnostic(docs.SeverityError, err))
 }
 
-func newCompileContext(input *docs.Document, output *Document) *CompileContext {
-	return &CompileContext{
-		in:  input,
-		out: output,
-	}
+func (c *CompileContext) Diagnostics() docs.Diagnostics {
+	return c.diagnostics
 }
 
 func (c *CompileContext) CompileInfo() {
 	c.out.Info.Title = c.in.Info.Title
 	c.out.Info.Version = c.in.Info.Version
+	c.out.Info.Description = c.in.Info.Description
+	c.out.Info.Extensions = copyExtensions(c.in.Info.Extensions)
 }
 
 func (c *CompileContext) CompileServers() {
@@ -95,6 +159,7 @@ func (c *CompileContext) CompileServers() {
 		return Server{
 			Url:         in.Url,
 			Description: in.Description,
+			Extensions:  copyExtensions(in.Extensions),
 		}
 	})
 }
@@ -104,6 +169,7 @@ func (c *CompileContext) CompileTags() {
 		return Tag{
 			Name:        in.Name,
 			Description: in.Description,
+			Extensions:  copyExtensions(in.Extensions),
 		}
 	})
 }
@@ -111,17 +177,22 @@ func (c *CompileContext) CompileTags() {
 func (c *CompileContext) ParseSchema(schema docs.Schema) (SchemaOrRef, error) {
 	switch v := schema.Value.(type) {
 	case string: // expr
-		return parseSchema(v)
+		out, err := parseSchema(v)
+		if err != nil {
+			return SchemaOrRef{}, err
+		}
+		return c.resolveExternalRefs(out)
 	case docs.Properties:
 		object := Schema{
 			Type:       SchemaObject,
 			Required:   make([]string, 0),
 			Properties: make(Properties, 0),
+			Extensions: copyExtensions(schema.Extensions),
 		}
 		for _, property := range v {
 			name, opt := strings.CutSuffix(property.Name, "?")
 			if schema, err := c.ParseSchema(property.Schema); err != nil {
-				return SchemaOrRef{}, err
+				return SchemaOrRef{}, docs.At(err, property.Name)
 			} else {
 				object.Properties = append(object.Properties, Property{
 					Name:   name,
@@ -139,145 +210,210 @@ func (c *CompileContext) ParseSchema(schema docs.Schema) (SchemaOrRef, error) {
 	}
 }
 
-func (c *CompileContext) ParseSchemas() error {
+func (c *CompileContext) CompileSecuritySchemes() {
+	if len(c.in.SecuritySchemes) == 0 {
+		return
+	}
+
+	c.out.Components.SecuritySchemes = make(map[string]SecurityScheme, len(c.in.SecuritySchemes))
+
+	for name, scheme := range c.in.SecuritySchemes {
+		c.out.Components.SecuritySchemes[name] = SecurityScheme{
+			Type:             scheme.Type,
+			Description:      scheme.Description,
+			Name:             scheme.Name,
+			In:               scheme.In,
+			Scheme:           scheme.Scheme,
+			BearerFormat:     scheme.BearerFormat,
+			Flows:            scheme.Flows,
+			OpenIdConnectUrl: scheme.OpenIdConnectUrl,
+		}
+	}
+}
+
+func (c *CompileContext) ParseSchemas() {
 
 	if c.out.Components.Schemas == nil {
 		c.out.Components.Schemas = make(map[string]Schema)
 	}
 
-	for name, schema := range c.in.Schemas {
+	// sorted, so external schemas are bundled deterministically
+	for _, name := range slices.Sorted(maps.Keys(c.in.Schemas)) {
 		var schemaOrRef SchemaOrRef
-		if sch, err := c.ParseSchema(schema); err != nil {
-			return err
+		if sch, err := c.ParseSchema(c.in.Schemas[name]); err != nil {
+			c.report(docs.At(err, "schemas", name))
+			continue
 		} else {
 			schemaOrRef = sch
 		}
 
 		schema, ok := schemaOrRef.value.(Schema)
 		if !ok {
-			return fmt.Errorf("Schema ref when Schema expected")
+			c.report(docs.Errorf(docs.NodePath{"schemas", name}, "Schema ref when Schema expected"))
+			continue
 		}
+		schema.pos = c.position(docs.NodePath{"schemas", name})
 		c.out.Components.Schemas[name] = schema
 	}
-	return nil
 }
 
-func (c *CompileContext) ParseDefaultResponses() error {
+func (c *CompileContext) parseHeader(header docs.Param) (Header, error) {
+	schema, err := c.ParseSchema(header.Schema)
+	if err != nil {
+		return Header{}, docs.At(err, "schema")
+	}
 
-	c.defaultResponses = make(map[StatusCode]Response, len(c.in.DefaultResponses))
+	return Header{
+		Required:   header.Required,
+		Schema:     schema,
+		Extensions: copyExtensions(header.Extensions),
+	}, nil
+}
 
-	for statusCode, response := range c.in.DefaultResponses {
-		outResponse := Response{
-			Description: response.Description,
-			Content:     make(map[string]TypedSchema),
-		}
+// parseResponse compiles response at given path, errors are located at it.
+// Links are located at linksAt, the response declaring them, which is the
+// trait definition for responses of traits.
+func (c *CompileContext) parseResponse(response docs.Response, at docs.NodePath, linksAt docs.NodePath) (Response, error) {
+	outResponse := Response{
+		Description: response.Description,
+		Extensions:  copyExtensions(response.Extensions),
+	}
+
+	if len(response.TypedSchema) != 0 {
+		outResponse.Content = make(map[string]TypedSchema)
+
+		for _, mediaType := range slices.Sorted(maps.Keys(response.TypedSchema)) {
+			outSchema, err := c.ParseSchema(response.TypedSchema[mediaType])
 
-		for t, sch := range response.TypedSchema {
-			schema, err := c.ParseSchema(sch)
 			if err != nil {
-				return err
+				return Response{}, docs.At(err, slices.Concat(at, docs.NodePath{mediaType})...)
 			}
 
-			outResponse.Content[t] = TypedSchema{
-				Schema: schema,
+			outResponse.Content[mediaType] = TypedSchema{
+				Schema: outSchema,
 			}
 		}
+	}
 
-		c.defaultResponses[statusCode] = outResponse
+	if len(response.Headers) != 0 {
+		outResponse.Headers = make(map[string]Header, len(response.Headers))
+
+		for idx, header := range response.Headers {
+			outHeader, err := c.parseHeader(header)
+			if err != nil {
+				return Response{}, docs.At(err, slices.Concat(at, docs.NodePath{"headers", strconv.Itoa(idx)})...)
+			}
+			outResponse.Headers[header.Name] = outHeader
+		}
 	}
 
-	return nil
+	outResponse.Links = c.parseLinks(response.Links, linksAt)
+
+	return outResponse, nil
 }
 
-var traitEvExpr = regexp.MustCompile(`^([A-Za-z_]\w*)(?:\(\s*([^()]+?)\s*\))?$`)
+// parseDefaultResponseGroup reports invalid responses and skips them, so
+// group is usable even when some of its responses failed
+func (c *CompileContext) parseDefaultResponseGroup(responses docs.Responses, at docs.NodePath) map[StatusCode]Response {
+	out := make(map[StatusCode]Response, len(responses))
 
-func (c *CompileContext) compileTrait(args string, t docs.Trait) (PrecompiledTrait, error) {
-	splited := strings.Split(args, ",")
-	argsOut := make([]string, len(splited))
+	for _, statusCode := range slices.Sorted(maps.Keys(responses)) {
+		responseAt := slices.Concat(at, docs.NodePath{statusCode})
+		outResponse, err := c.parseResponse(responses[statusCode], responseAt, responseAt)
+		if err != nil {
+			c.report(err)
+			continue
+		}
 
-	for idx, arg := range splited {
-		arg = strings.TrimSpace(arg)
-		argsOut[idx] = arg
+		out[statusCode] = outResponse
 	}
 
-	return PrecompiledTrait{
-		args:   argsOut,
-		target: t,
-	}, nil
+	return out
 }
 
-func (c *CompileContext) compileTraits() error {
-	c.compiledTraits = make(map[string]PrecompiledTrait, len(c.in.Traits))
+func (c *CompileContext) ParseDefaultResponses() {
 
-	for expr, trait := range c.in.Traits {
-		exprGrp := traitEvExpr.FindStringSubmatch(expr)
-		if exprGrp == nil {
-			return fmt.Errorf("invalid trait definition expression: %v", expr)
-		}
-		ident := exprGrp[1]
-		args := exprGrp[2]
+	c.defaultResponses = make(map[string]map[StatusCode]Response, len(c.in.DefaultResponseGroups)+1)
 
-		result, err := c.compileTrait(args, trait)
+	c.defaultResponses[docs.DefaultGroup] = c.parseDefaultResponseGroup(c.in.DefaultResponses, docs.NodePath{"defaultResponses"})
 
-		if err != nil {
-			return err
+	for _, name := range slices.Sorted(maps.Keys(c.in.DefaultResponseGroups)) {
+		if name == docs.DefaultGroup {
+			c.report(docs.Errorf(docs.NodePath{"defaultResponseGroups", docs.DefaultGroup}, "default response group name %q is reserved for defaultResponses", docs.DefaultGroup))
+			continue
 		}
-
-		c.compiledTraits[ident] = result
+		c.defaultResponses[name] = c.parseDefaultResponseGroup(c.in.DefaultResponseGroups[name], docs.NodePath{"defaultResponseGroups", name})
 	}
-	return nil
 }
 
-func (c *CompileContext) evaluateTrait(expr string) (docs.Trait, error) {
-	groups := traitEvExpr.FindStringSubmatch(expr)
-
-	if groups == nil {
-		return docs.Trait{}, fmt.Errorf("invalid trait evaluate expression: %v\n expected: ident[(arg(,args)...)]", expr)
-	}
-
-	ident := groups[1]
-	params := groups[2]
+// defaultsScope holds default response groups and excluded status codes
+// inherited down the paths tree.
+type defaultsScope struct {
+	groups  []string
+	exclude []StatusCode
+}
 
-	trait, has := c.compiledTraits[ident]
+var rootDefaultsScope = defaultsScope{
+	groups: []string{docs.DefaultGroup},
+}
 
-	if !has {
-		return docs.Trait{}, fmt.Errorf("no %v trait found", ident)
+func (s defaultsScope) with(policy *docs.DefaultResponsesPolicy) defaultsScope {
+	if policy == nil {
+		return s
 	}
 
-	values := make([]string, 0)
+	out := defaultsScope{
+		groups:  s.groups,
+		exclude: slices.Concat(s.exclude, policy.Exclude),
+	}
 
-	for param := range strings.SplitSeq(params, ",") {
-		param = strings.TrimSpace(param)
-		values = append(values, param)
+	if policy.Groups != nil {
+		out.groups = policy.Groups
 	}
 
-	return trait.Compile(values)
+	out.groups = slices.Concat(out.groups, policy.Include)
 
+	return out
 }
 
-func (c *CompileContext) evaluateTraits(traits []string) ([]docs.Trait, error) {
-	if traits == nil {
-		return nil, nil
-	}
-
-	var out []docs.Trait = make([]docs.Trait, len(traits))
+// defaultResponsesFor merges groups of scope in order, later groups override
+// the same status codes of earlier ones.
+func (c *CompileContext) defaultResponsesFor(scope defaultsScope) (map[StatusCode]Response, error) {
+	out := make(map[StatusCode]Response)
 
-	for idx, in := range traits {
-		res, err := c.evaluateTrait(in)
-		if err != nil {
-			return nil, err
+	for _, name := range scope.groups {
+		group, has := c.defaultResponses[name]
+		if !has {
+			return nil, fmt.Errorf("no %v default response group found", name)
 		}
-		out[idx] = res
+		maps.Copy(out, group)
+	}
+
+	for _, code := range scope.exclude {
+		delete(out, code)
 	}
 
 	return out, nil
 }
 
-func (c *CompileContext) parseMethod(method *docs.Method, tags []string, path string) (*Operation, error) {
+// parseMethod compiles method at given node path, errors are located at it
+func (c *CompileContext) parseMethod(method *docs.Method, tags []string, inheritedTraits []string, path string, at docs.NodePath, scope defaultsScope) (*Operation, error) {
 	if method == nil {
 		return nil, nil
 	}
 
+	// located relative to method
+	fail := func(err error, segments ...string) (*Operation, error) {
+		return nil, docs.At(err, slices.Concat(at, segments)...)
+	}
+
+	scope = scope.with(method.DefaultResponses)
+
+	defaults, err := c.defaultResponsesFor(scope)
+	if err != nil {
+		return fail(err)
+	}
+
 	makeParam := func(p *docs.Param, in ParamIn) (Parameter, error) {
 		// query is path in {name} in path
 		if in == InQuery && strings.Contains(path, "{"+p.Name+"}") {
@@ -286,197 +422,463 @@ func (c *CompileContext) parseMethod(method *docs.Method, tags []string, path st
 
 		schema, err := c.ParseSchema(p.Schema)
 		if err != nil {
-			return Parameter{}, err
+			return Parameter{}, docs.At(err, "schema")
 		}
 
 		return Parameter{
-			Name:     p.Name,
-			In:       in,
-			Required: p.Required,
-			Schema:   schema,
+			Name:       p.Name,
+			In:         in,
+			Required:   p.Required,
+			Schema:     schema,
+			Extensions: copyExtensions(p.Extensions),
 		}, nil
 	}
 
-	traits, err := c.evaluateTraits(method.Traits)
+	calls, err := c.methodTraits(method, inheritedTraits)
+	if err != nil {
+		return fail(err, "excludeTraits")
+	}
+
+	traits, err := c.evaluateTraits(calls)
 
 	if err != nil {
-		return nil, err
+		return fail(fmt.Errorf("operation %v: %w", operationLabel(method, path, at), err), "traits")
 	}
 
 	out := Operation{
 		OperationId: method.Id,
 		Summary:     method.Description,
-		Tags:        tags,
+		Deprecated:  method.Deprecated,
+		Tags:        slices.Clone(tags),
 		Parameters:  make([]Parameter, 0),
-		Responses:   maps.Clone(c.defaultResponses),
+		Responses:   defaults,
+		Extensions:  copyExtensions(method.Extensions),
+		at:          at,
 	}
 
-	// params to parameters
-
-	if method.Params != nil {
-		for _, v := range method.Params {
-			outParam, err := makeParam(&v, InQuery)
+	// method's own params take precedence over trait's params of the same
+	// name and location, later traits replace params of earlier ones
+	addParam := func(p *docs.Param, in ParamIn, own int) error {
+		outParam, err := makeParam(p, in)
+		if err != nil {
+			return err
+		}
 
-			if err != nil {
-				return nil, err
-			}
+		idx := slices.IndexFunc(out.Parameters, func(param Parameter) bool {
+			return param.Name == outParam.Name && param.In == outParam.In
+		})
 
+		switch {
+		case idx == -1:
 			out.Parameters = append(out.Parameters, outParam)
+		case idx >= own:
+			out.Parameters[idx] = outParam
 		}
+		return nil
 	}
 
-	// headers to parameters
+	// params to parameters
 
-	if method.Headers != nil {
-		for _, header := range method.Headers {
+	for idx, v := range method.Params {
+		if err := addParam(&v, InQuery, 0); err != nil {
+			return fail(err, "params", strconv.Itoa(idx))
+		}
+	}
 
-			outParam, err := makeParam(&header, InHeader)
-			if err != nil {
-				return nil, err
-			}
+	// headers to parameters
 
-			out.Parameters = append(out.Parameters, outParam)
+	for idx, header := range method.Headers {
+		if err := addParam(&header, InHeader, 0); err != nil {
+			return fail(err, "headers", strconv.Itoa(idx))
 		}
 	}
 
 	// put trait's params / headers into operation
 
+	own := len(out.Parameters)
+
 	for _, t := range traits {
 		for _, param := range t.Params {
-			outParam, err := makeParam(&param, InQuery)
-			if err != nil {
-				return nil, err
+			if err := addParam(&param, InQuery, own); err != nil {
+				return fail(err, "traits")
 			}
-			out.Parameters = append(out.Parameters, outParam)
 		}
 		for _, header := range t.Headers {
-			outHeader, err := makeParam(&header, InHeader)
-			if err != nil {
-				return nil, err
+			if err := addParam(&header, InHeader, own); err != nil {
+				return fail(err, "traits")
 			}
-			out.Parameters = append(out.Parameters, outHeader)
 		}
 	}
 
-	if method.Body != nil {
-		body := RequestBody{
+	// body: method's body over later traits over earlier traits
+
+	body, bodyAt := method.Body, docs.NodePath{"body"}
+
+	if body == nil {
+		for _, t := range traits {
+			if t.Body != nil {
+				body, bodyAt = t.Body, docs.NodePath{"traits"}
+			}
+		}
+	}
+
+	if body != nil {
+		outBody := RequestBody{
 			Required: true,
-			Content:  make(map[string]TypedSchema, len(method.Body)),
+			Content:  make(map[string]TypedSchema, len(body)),
 		}
 
-		for t, s := range method.Body {
-			schema, err := c.ParseSchema(s)
+		for _, t := range slices.Sorted(maps.Keys(body)) {
+			schema, err := c.ParseSchema(body[t])
 			if err != nil {
-				return nil, err
+				if bodyAt[0] == "body" {
+					return fail(err, "body", t)
+				}
+				return fail(err, bodyAt...)
 			}
 
-			body.Content[t] = TypedSchema{
+			outBody.Content[t] = TypedSchema{
 				Schema: schema,
 			}
 		}
 
-		out.RequestBody = &body
+		out.RequestBody = &outBody
+	}
+
+	// responses: defaults < traits (later wins) < method, replaced per status code
+
+	for _, t := range traits {
+		for _, statusCode := range slices.Sorted(maps.Keys(t.Responses)) {
+			outResponse, err := c.parseResponse(t.Responses[statusCode], slices.Concat(at, docs.NodePath{"traits"}), slices.Concat(t.at, docs.NodePath{"responses", statusCode}))
+			if err != nil {
+				return nil, err
+			}
+			out.Responses[statusCode] = outResponse
+		}
 	}
 
-	for statusCode, response := range method.Responses {
-		outResponse := Response{
-			Description: response.Description,
+	for _, statusCode := range slices.Sorted(maps.Keys(method.Responses)) {
+		responseAt := slices.Concat(at, docs.NodePath{"responses", statusCode})
+		outResponse, err := c.parseResponse(method.Responses[statusCode], responseAt, responseAt)
+		if err != nil {
+			return nil, err
 		}
+		// inherited responses follow the method's own ones in source order
+		outResponse.pos = c.position(responseAt)
+		out.Responses[statusCode] = outResponse
+	}
 
-		if len(response.TypedSchema) != 0 {
-			outResponse.Content = make(map[string]TypedSchema)
+	// trait's response headers go to every 2XX response, unless already defined
 
-			for mediaType, schema := range response.TypedSchema {
-				outSchema, err := c.ParseSchema(schema)
+	for _, t := range traits {
+		for _, header := range t.ResponseHeaders {
+			outHeader, err := c.parseHeader(header)
+			if err != nil {
+				return fail(err, "traits")
+			}
+
+			for statusCode, response := range out.Responses {
+				if !strings.HasPrefix(statusCode, "2") {
+					continue
+				}
 
-				if err != nil {
-					return nil, err
+				if _, has := response.Headers[header.Name]; has {
+					continue
 				}
 
-				outResponse.Content[mediaType] = TypedSchema{
-					Schema: outSchema,
+				response.Headers = maps.Clone(response.Headers)
+				if response.Headers == nil {
+					response.Headers = make(map[string]Header)
 				}
+				response.Headers[header.Name] = outHeader
+				out.Responses[statusCode] = response
 			}
 		}
+	}
 
-		out.Responses[statusCode] = outResponse
+	// tags: path's tags, then trait's tags
+
+	for _, t := range traits {
+		for _, tag := range t.Tags {
+			if !slices.Contains(out.Tags, tag) {
+				out.Tags = append(out.Tags, tag)
+			}
+		}
+	}
+
+	// security: method's security replaces trait's security requirements
+
+	security, securityAt := method.Security, "security"
+
+	if security == nil {
+		securityAt = "traits"
+		for _, t := range traits {
+			security = append(security, t.Security...)
+		}
+	}
+
+	for _, requirement := range security {
+		for name := range requirement {
+			if _, has := c.in.SecuritySchemes[name]; !has {
+				return fail(fmt.Errorf("no %v security scheme found", name), securityAt)
+			}
+		}
+		out.Security = append(out.Security, requirement)
+	}
+
+	out.Callbacks = c.parseCallbacks(method.Callbacks, slices.Concat(at, docs.NodePath{"callbacks"}), scope)
+
+	// operations sharing id are reported by checkOperationIds
+	if _, has := c.operations[out.OperationId]; out.OperationId != "" && !has {
+		c.operations[out.OperationId] = &out
 	}
 
 	return &out, nil
 }
 
-func (c *CompileContext) ParsePaths() error {
+// operationLabel names operation in errors, by id if given, otherwise by
+// HTTP method and path, e.g. POST /users, at is node of the method
+func operationLabel(method *docs.Method, path string, at docs.NodePath) string {
+	if method.Id != "" {
+		return method.Id
+	}
+	return strings.ToUpper(at[len(at)-1]) + " " + path
+}
+
+func hasAnyMethod(p *docs.Path) bool {
+	collected := []*docs.Method{p.Get, p.Post, p.Put, p.Patch, p.Delete}
+	for _, v := range collected {
+		if v != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePathItem compiles methods of single path node, without nested paths,
+// scope must already include the node's default responses policy
+func (c *CompileContext) parsePathItem(current docs.Path, currentPath string, at docs.NodePath, scope defaultsScope) Path {
+	outPath := Path{
+		Summary:    "", //TODO: remove it or use later
+		Extensions: copyExtensions(current.Extensions),
+		pos:        c.position(at),
+	}
+
+	methodAt := func(name string) docs.NodePath {
+		return slices.Concat(at, docs.NodePath{name})
+	}
+
+	if op, err := c.parseMethod(current.Get, current.Tags, current.Traits.ForMethod("get"), currentPath, methodAt("get"), scope); err != nil {
+		c.report(err)
+	} else {
+		outPath.Get = op
+	}
+
+	if op, err := c.parseMethod(current.Post, current.Tags, current.Traits.ForMethod("post"), currentPath, methodAt("post"), scope); err != nil {
+		c.report(err)
+	} else {
+		outPath.Post = op
+	}
+
+	if op, err := c.parseMethod(current.Put, current.Tags, current.Traits.ForMethod("put"), currentPath, methodAt("put"), scope); err != nil {
+		c.report(err)
+	} else {
+		outPath.Put = op
+	}
+
+	if op, err := c.parseMethod(current.Patch, current.Tags, current.Traits.ForMethod("patch"), currentPath, methodAt("patch"), scope); err != nil {
+		c.report(err)
+	} else {
+		outPath.Patch = op
+	}
+
+	if op, err := c.parseMethod(current.Delete, current.Tags, current.Traits.ForMethod("delete"), currentPath, methodAt("delete"), scope); err != nil {
+		c.report(err)
+	} else {
+		outPath.Delete = op
+	}
+
+	return outPath
+}
+
+func (c *CompileContext) ParsePaths() {
 
 	c.out.Paths = make(map[string]Path)
 
-	hasAnyMethod := func(p *docs.Path) bool {
-		collected := []*docs.Method{p.Get, p.Post, p.Put, p.Patch, p.Delete}
-		for _, v := range collected {
-			if v != nil {
-				return true
-			}
+	// mountPath is path of the closest node mounting a file, empty when
+	// there is none
+	var collectPaths func(currentPath, mountPath string, at docs.NodePath, p docs.Path, scope defaultsScope)
+
+	collectPaths = func(currentPath, mountPath string, at docs.NodePath, current docs.Path, scope defaultsScope) {
+		scope = scope.with(current.DefaultResponses)
+
+		if current.Mount != "" {
+			mountPath = currentPath
+		}
+
+		if hasAnyMethod(&current) {
+			item := c.parsePathItem(current, currentPath, at, scope)
+			addMountParams(item, mountPath)
+			c.out.Paths[currentPath] = item
+		}
+
+		for _, nextPath := range slices.Sorted(maps.Keys(current.Nested)) {
+			next := current.Nested[nextPath]
+			next.Tags = append(next.Tags, current.Tags...)
+			next.Traits = next.Traits.Inherit(current.Traits)
+			collectPaths(path.Join(currentPath, nextPath), mountPath, slices.Concat(at, docs.NodePath{nextPath}), next, scope)
 		}
-		return false
 	}
 
-	var collectPaths func(currentPath string, p docs.Path) error
+	// sorted, so external schemas are bundled deterministically
+	for _, currentPath := range slices.Sorted(maps.Keys(c.in.Paths)) {
+		collectPaths(currentPath, "", docs.NodePath{"paths", currentPath}, c.in.Paths[currentPath], rootDefaultsScope)
+	}
+}
 
-	collectPaths = func(currentPath string, current docs.Path) error {
-		if hasAnyMethod(&current) {
-			outPath := Path{
-				Summary: "", //TODO: remove it or use later
+// addMountParams adds path params of mount point to operations of item
+// which don't declare them, mounted files don't know where they are mounted.
+// Params are strings and come first, as in the path, unless declared by an
+// inherited trait.
+func addMountParams(item Path, mountPath string) {
+	for _, op := range item.operations() {
+		var missing []Parameter
+
+		for _, segment := range strings.Split(mountPath, "/") {
+			if !isParamSegment(segment) {
+				continue
 			}
+			name := segment[1 : len(segment)-1]
 
-			if op, err := c.parseMethod(current.Get, current.Tags, currentPath); err != nil {
-				return fmt.Errorf("unable to parse method: %v", err)
-			} else {
-				outPath.Get = op
+			if slices.ContainsFunc(op.Parameters, func(p Parameter) bool { return p.In == InPath && p.Name == name }) {
+				continue
 			}
 
-			if op, err := c.parseMethod(current.Post, current.Tags, currentPath); err != nil {
-				return fmt.Errorf("unable to parse method: %v", err)
-			} else {
-				outPath.Post = op
-			}
+			missing = append(missing, Parameter{
+				Name:     name,
+				In:       InPath,
+				Required: true,
+				Schema:   NewSchemaDef(Schema{Type: SchemaString}),
+			})
+		}
 
-			if op, err := c.parseMethod(current.Put, current.Tags, currentPath); err != nil {
-				return fmt.Errorf("unable to parse method: %v", err)
-			} else {
-				outPath.Put = op
-			}
+		op.Parameters = slices.Concat(missing, op.Parameters)
+	}
+}
 
-			if op, err := c.parseMethod(current.Patch, current.Tags, currentPath); err != nil {
-				return fmt.Errorf("unable to parse method: %v", err)
-			} else {
-				outPath.Patch = op
-			}
+func (c *CompileContext) ParseWebhooks() {
+	if len(c.in.Webhooks) == 0 {
+		return
+	}
 
-			if op, err := c.parseMethod(current.Delete, current.Tags, currentPath); err != nil {
-				return fmt.Errorf("unable to parse method: %v", err)
-			} else {
-				outPath.Delete = op
-			}
+	c.out.Webhooks = make(map[string]Path, len(c.in.Webhooks))
 
-			c.out.Paths[currentPath] = outPath
-		}
+	for _, name := range slices.Sorted(maps.Keys(c.in.Webhooks)) {
+		webhook := c.in.Webhooks[name]
+		c.out.Webhooks[name] = c.parsePathItem(webhook, name, docs.NodePath{"webhooks", name}, rootDefaultsScope.with(webhook.DefaultResponses))
+	}
+}
 
-		for nextPath, next := range current.Nested {
-			next.Tags = append(next.Tags, current.Tags...)
-			if err := collectPaths(path.Join(currentPath, nextPath), next); err != nil {
-				return err
-			}
+func (c *CompileContext) parseCallbacks(callbacks map[string]docs.Callback, at docs.NodePath, scope defaultsScope) map[string]Callback {
+	if len(callbacks) == 0 {
+		return nil
+	}
+
+	out := make(map[string]Callback, len(callbacks))
+
+	for _, name := range slices.Sorted(maps.Keys(callbacks)) {
+		callback := callbacks[name]
+		outCallback := make(Callback, len(callback))
+
+		for _, expr := range slices.Sorted(maps.Keys(callback)) {
+			item := callback[expr]
+			outCallback[expr] = c.parsePathItem(item, expr, slices.Concat(at, docs.NodePath{name, expr}), scope.with(item.DefaultResponses))
 		}
+
+		out[name] = outCallback
+	}
+
+	return out
+}
+
+func (c *CompileContext) parseLinks(links docs.Links, at docs.NodePath) map[string]Link {
+	if len(links) == 0 {
 		return nil
 	}
 
-	for currentPath, current := range c.in.Paths {
-		if err := collectPaths(currentPath, current); err != nil {
-			return fmt.Errorf("unable to collect paths: %v", err)
+	out := make(map[string]Link, len(links))
+
+	for name, link := range links {
+		c.links = append(c.links, pendingLink{
+			name: name,
+			at:   at,
+			link: link,
+		})
+
+		out[name] = Link{
+			OperationId: link.Operation,
+			Parameters:  link.Params,
+			RequestBody: link.Body,
+			Description: link.Description,
+		}
+	}
+
+	return out
+}
+
+// hasParameter reports whether operation has parameter, name can be
+// qualified with location, e.g. path.id
+func (o *Operation) hasParameter(name string) bool {
+	var in ParamIn
+
+	if loc, rest, ok := strings.Cut(name, "."); ok {
+		switch ParamIn(loc) {
+		case InPath, InQuery, InHeader:
+			in, name = ParamIn(loc), rest
+		}
+	}
+
+	for _, param := range o.Parameters {
+		if param.Name == name && (in == "" || param.In == in) {
+			return true
 		}
 	}
-	return nil
+	return false
 }
 
+// ValidateLinks checks links against their target operations, links of
+// traits are validated once, not for every method applying them
+func (c *CompileContext) ValidateLinks() {
+	validated := make(map[string]bool, len(c.links))
+
+	for _, pending := range c.links {
+		linkAt := slices.Concat(pending.at, docs.NodePath{"links", pending.name})
+
+		if validated[linkAt.String()] {
+			continue
+		}
+		validated[linkAt.String()] = true
+
+		target, has := c.operations[pending.link.Operation]
+		if !has {
+			c.report(docs.Errorf(slices.Concat(linkAt, docs.NodePath{"operation"}), "link %v: operation %v not found", pending.name, pending.link.Operation))
+			continue
+		}
+
+		for param := range pending.link.Params {
+			if !target.hasParameter(param) {
+				c.report(docs.Errorf(slices.Concat(linkAt, docs.NodePath{"params", param}), "link %v: operation %v has no parameter %v", pending.name, pending.link.Operation, param))
+			}
+		}
+
+		if pending.link.Body != nil && target.RequestBody == nil {
+			c.report(docs.Errorf(slices.Concat(linkAt, docs.NodePath{"body"}), "link %v: operation %v has no request body", pending.name, pending.link.Operation))
+		}
+	}
+}
+
+// Parse compiles whole document, it doesn't stop on first error, all of them
+// are collected in diagnostics. Returned error contains all errors found.
 func (c *CompileContext) Parse() error {
 	c.CompileInfo()
 
@@ -484,42 +886,103 @@ func (c *CompileContext) Parse() error {
 
 	c.CompileTags()
 
-	if err := c.ParseSchemas(); err != nil {
-		return err
+	c.CompileSecuritySchemes()
+
+	c.ParseSchemas()
+
+	c.ParseDefaultResponses()
+
+	c.compileTraits()
+
+	c.ParsePaths()
+
+	c.ParseWebhooks()
+
+	if c.generateIds {
+		c.generateOperationIds()
 	}
 
-	if err := c.ParseDefaultResponses(); err != nil {
-		return err
+	c.checkOperationIds()
+
+	c.ValidateLinks()
+
+	c.checkReachability()
+
+	return c.diagnostics.Err()
+}
+
+// compile parses in into out, downgraded when target is older OpenAPI
+func compile(out *Document, in *docs.Document, opts Options) (*CompileContext, error) {
+	ctx, err := newCompileContext(in, out, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := c.compileTraits(); err != nil {
-		return err
+	out.Openapi = opts.Target.version()
+
+	if err := ctx.Parse(); err != nil {
+		return ctx, err
 	}
 
-	if err := c.ParsePaths(); err != nil {
-		return err
+	switch opts.Target {
+	case TargetOpenAPI30, TargetSwagger2:
+		ctx.downgradeTo30(opts.Target)
 	}
 
-	return nil
+	return ctx, nil
 }
 
-func Compile(out *Document, in *docs.Document) error {
-	ctx := newCompileContext(in, out)
+// Compile compiles in into out, returned diagnostics contain all problems
+// found, including the ones returned as error
+func Compile(out *Document, in *docs.Document, opts Options) (docs.Diagnostics, error) {
+	if opts.Target == TargetSwagger2 {
+		return nil, fmt.Errorf("%v target is compiled by CompileSwagger2", TargetSwagger2)
+	}
 
-	if err := ctx.Parse(); err != nil {
-		return err
+	ctx, err := compile(out, in, opts)
+
+	return ctxDiagnostics(ctx), err
+}
+
+// CompileSwagger2 compiles in into Swagger 2.0 document, constructs which
+// can't be expressed are reported as warnings
+func CompileSwagger2(out *Swagger2Document, in *docs.Document, opts Options) (docs.Diagnostics, error) {
+	opts.Target = TargetSwagger2
+
+	ctx, err := compile(&Document{}, in, opts)
+	if err != nil {
+		return ctxDiagnostics(ctx), err
 	}
 
-	return nil
+	*out = ctx.toSwagger2()
+
+	return ctx.Diagnostics(), nil
 }
 
-func CompileToJSON(in *docs.Document) ([]byte, error) {
-	out := Document{
-		Openapi: "3.1.0",
+func ctxDiagnostics(ctx *CompileContext) docs.Diagnostics {
+	if ctx == nil {
+		return nil
 	}
+	return ctx.Diagnostics()
+}
 
-	if err := Compile(&out, in); err != nil {
-		return nil, err
+// compileTarget compiles in into model of target format
+func compileTarget(in *docs.Document, opts Options) (any, docs.Diagnostics, error) {
+	if opts.Target == TargetSwagger2 {
+		var out Swagger2Document
+		diagnostics, err := CompileSwagger2(&out, in, opts)
+		return out, diagnostics, err
+	}
+
+	var out Document
+	diagnostics, err := Compile(&out, in, opts)
+	return out, diagnostics, err
+}
+
+func CompileToJSON(in *docs.Document, opts Options) ([]byte, docs.Diagnostics, error) {
+	out, diagnostics, err := compileTarget(in, opts)
+	if err != nil {
+		return nil, diagnostics, err
 	}
 
 	bytes, err := json.Marshal(out)
@@ -528,16 +991,13 @@ func CompileToJSON(in *docs.Document) ([]byte, error) {
 		panic(err) // should be ok
 	}
 
-	return bytes, nil
+	return bytes, diagnostics, nil
 }
 
-func CompileToYAML(in *docs.Document) ([]byte, error) {
-	out := Document{
-		Openapi: "3.1.0",
-	}
-
-	if err := Compile(&out, in); err != nil {
-		return nil, err
+func CompileToYAML(in *docs.Document, opts Options) ([]byte, docs.Diagnostics, error) {
+	out, diagnostics, err := compileTarget(in, opts)
+	if err != nil {
+		return nil, diagnostics, err
 	}
 
 	bytes, err := yaml.Marshal(out)
@@ -546,5 +1006,5 @@ func CompileToYAML(in *docs.Document) ([]byte, error) {
 		panic(err)
 	}
 
-	return bytes, nil
+	return bytes, diagnostics, nil
 }
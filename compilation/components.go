@@ -1,5 +1,6 @@
 package compilation
 
 type Components struct {
-	Schemas map[string]Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	Schemas         Schemas                   `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
 }
@@ -0,0 +1,42 @@
+package compilation
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// Extensions are vendor extension keys (x-*), inlined into the owning object.
+type Extensions map[string]any
+
+func copyExtensions(in docs.Extensions) Extensions {
+	if len(in) == 0 {
+		return nil
+	}
+	return Extensions(maps.Clone(in))
+}
+
+// marshalExtendedJSON marshals v and appends extensions to the resulting object.
+func marshalExtendedJSON(v any, ext Extensions) ([]byte, error) {
+	out, err := json.Marshal(v)
+	if err != nil || len(ext) == 0 {
+		return out, err
+	}
+
+	extBytes, err := json.Marshal(map[string]any(ext))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(out[:len(out)-1])
+
+	if len(out) > 2 {
+		buf.WriteByte(',')
+	}
+
+	buf.Write(extBytes[1:])
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,356 @@
+package compilation
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// places of named schemas in external documents, tried in order
+var externalSchemaPointers = []string{
+	"/components/schemas/",
+	"/$defs/",
+	"/definitions/",
+}
+
+// resolveExternalRefs compiles references to schemas of external files in s
+// according to the external refs mode
+func (c *CompileContext) resolveExternalRefs(s SchemaOrRef) (SchemaOrRef, error) {
+	var firstErr error
+
+	out := mapSchemaOrRef(s, func(s SchemaOrRef) SchemaOrRef {
+		ref, ok := s.GetRef()
+		if !ok || strings.HasPrefix(ref, "#") || firstErr != nil {
+			return s
+		}
+
+		file, pointer, err := c.externalTarget(ref)
+		if err == nil {
+			ref, err = c.externalRef(file, pointer)
+		}
+
+		if err != nil {
+			firstErr = err
+			return s
+		}
+		return NewSchemaRef(ref)
+	})
+
+	return out, firstErr
+}
+
+// externalTarget returns file and JSON pointer of external reference as
+// written in schema expression
+func (c *CompileContext) externalTarget(ref string) (string, string, error) {
+	var file, name string
+
+	if f, fragment, ok := strings.Cut(ref, "#"); ok {
+		file, name = f, fragment
+	} else {
+		alias, n, _ := strings.Cut(ref, ":")
+
+		f, has := c.in.Externals[alias]
+		if !has {
+			return "", "", fmt.Errorf("unknown external %v in reference <%v>", alias, ref)
+		}
+		file, name = f, n
+	}
+
+	// same file is loaded and bundled once, however it's written
+	file = path.Clean(file)
+
+	document, err := c.loadExternal(file)
+	if err != nil {
+		return "", "", err
+	}
+
+	if strings.HasPrefix(name, "/") {
+		if _, has := jsonPointer(document, name); !has {
+			return "", "", fmt.Errorf("%v not found in %v", name, file)
+		}
+		return file, name, nil
+	}
+
+	for _, prefix := range externalSchemaPointers {
+		if _, has := jsonPointer(document, prefix+name); has {
+			return file, prefix + name, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("schema %v not found in %v", name, file)
+}
+
+// loadExternal reads YAML or JSON file relative to base directory, files
+// are read once
+func (c *CompileContext) loadExternal(file string) (any, error) {
+	if document, has := c.externals[file]; has {
+		if document == nil {
+			return nil, fmt.Errorf("unable to read external file %v", file)
+		}
+		return document, nil
+	}
+
+	c.externals[file] = nil
+
+	data, err := os.ReadFile(filepath.Join(c.baseDir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read external file: %w", err)
+	}
+
+	var document any
+	if err := yaml.UnmarshalWithOptions(data, &document, yaml.UseOrderedMap()); err != nil {
+		return nil, fmt.Errorf("unable to parse external file %v: %w", file, err)
+	}
+
+	c.externals[file] = document
+	return document, nil
+}
+
+// outputPath returns path of file relative to base directory as relative
+// to output directory
+func (c *CompileContext) outputPath(file string) string {
+	if filepath.IsAbs(file) || c.outputDir == c.baseDir {
+		return file
+	}
+
+	base, err := filepath.Abs(c.baseDir)
+	if err != nil {
+		return file
+	}
+
+	output, err := filepath.Abs(c.outputDir)
+	if err != nil {
+		return file
+	}
+
+	rel, err := filepath.Rel(output, filepath.Join(base, filepath.FromSlash(file)))
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// externalRef returns reference to schema at pointer of file, bundled to
+// components when requested
+func (c *CompileContext) externalRef(file, pointer string) (string, error) {
+	if c.externalRefs == ExternalRef {
+		return c.outputPath(file) + "#" + pointer, nil
+	}
+
+	key := file + "#" + pointer
+
+	if name, has := c.bundled[key]; has {
+		return componentsSchemaPrefix + name, nil
+	}
+
+	name := unescapePointer(path.Base(pointer))
+	if name == "" || name == "/" {
+		return "", fmt.Errorf("external schema %v has no name to bundle it with", key)
+	}
+
+	if _, has := c.in.Schemas[name]; has {
+		return "", fmt.Errorf("external schema %v conflicts with schema %v of the document", key, name)
+	}
+
+	for other, bundled := range c.bundled {
+		if bundled == name {
+			return "", fmt.Errorf("external schemas %v and %v are both named %v", other, key, name)
+		}
+	}
+
+	// registered first, so recursive references are resolved
+	c.bundled[key] = name
+
+	document, _ := c.loadExternal(file)
+	raw, _ := jsonPointer(document, pointer)
+
+	schema, err := c.fromJSONSchema(raw, file)
+	if err != nil {
+		return "", fmt.Errorf("external schema %v: %w", key, err)
+	}
+
+	if c.out.Components.Schemas == nil {
+		c.out.Components.Schemas = make(Schemas)
+	}
+	c.out.Components.Schemas[name] = schema
+
+	return componentsSchemaPrefix + name, nil
+}
+
+// fromJSONSchema converts JSON Schema of external file, structure qapi
+// schemas use is converted, other keywords are kept verbatim. References
+// are resolved relative to file.
+func (c *CompileContext) fromJSONSchema(raw any, file string) (Schema, error) {
+	var out Schema
+
+	if b, ok := raw.(bool); ok {
+		if !b {
+			out.Extensions = Extensions{"not": map[string]any{}}
+		}
+		return out, nil
+	}
+
+	object, ok := raw.(yaml.MapSlice)
+	if !ok {
+		return Schema{}, fmt.Errorf("schema must be an object, got %T", raw)
+	}
+
+	var firstErr error
+
+	// resolves reference relative to file
+	resolve := func(ref string) string {
+		target, pointer, _ := strings.Cut(ref, "#")
+
+		if target == "" {
+			target = file
+		} else {
+			target = path.Join(path.Dir(file), target)
+		}
+
+		if _, err := c.loadExternal(target); err != nil {
+			firstErr = err
+			return ref
+		}
+
+		resolved, err := c.externalRef(target, pointer)
+		if err != nil {
+			firstErr = err
+			return ref
+		}
+		return resolved
+	}
+
+	convert := func(raw any) SchemaOrRef {
+		if object, ok := raw.(yaml.MapSlice); ok && len(object) == 1 && object[0].Key == "$ref" {
+			if ref, ok := object[0].Value.(string); ok {
+				return NewSchemaRef(resolve(ref))
+			}
+		}
+
+		schema, err := c.fromJSONSchema(raw, file)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return NewSchemaDef(schema)
+	}
+
+	for _, item := range object {
+		key, _ := item.Key.(string)
+
+		switch value := item.Value.(type) {
+		case string:
+			switch key {
+			case "type":
+				out.Type = SchemaType(value)
+				continue
+			case "$ref":
+				if out.Extensions == nil {
+					out.Extensions = make(Extensions)
+				}
+				out.Extensions[key] = resolve(value)
+				continue
+			}
+		case yaml.MapSlice:
+			switch key {
+			case "properties":
+				out.Properties = make(Properties, 0, len(value))
+				for _, property := range value {
+					name, _ := property.Key.(string)
+					out.Properties = append(out.Properties, Property{
+						Name:   name,
+						Schema: convert(property.Value),
+					})
+				}
+				continue
+			case "items":
+				items := convert(value)
+				out.Items = &items
+				continue
+			}
+		case []any:
+			if key == "required" {
+				for _, name := range value {
+					if name, ok := name.(string); ok {
+						out.Required = append(out.Required, name)
+					}
+				}
+				continue
+			}
+		}
+
+		if out.Extensions == nil {
+			out.Extensions = make(Extensions)
+		}
+		out.Extensions[key] = plainJSON(item.Value, resolve)
+	}
+
+	return out, firstErr
+}
+
+// plainJSON converts decoded YAML to values marshalled as JSON, with
+// references rewritten by resolve
+func plainJSON(v any, resolve func(string) string) any {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		out := make(map[string]any, len(v))
+		for _, item := range v {
+			key := fmt.Sprint(item.Key)
+			if ref, ok := item.Value.(string); ok && key == "$ref" {
+				out[key] = resolve(ref)
+				continue
+			}
+			out[key] = plainJSON(item.Value, resolve)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = plainJSON(value, resolve)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// jsonPointer returns node of document at pointer
+func jsonPointer(document any, pointer string) (any, bool) {
+	node := document
+
+	for _, segment := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		segment = unescapePointer(segment)
+
+		switch n := node.(type) {
+		case yaml.MapSlice:
+			idx := slices.IndexFunc(n, func(item yaml.MapItem) bool {
+				return fmt.Sprint(item.Key) == segment
+			})
+			if idx == -1 {
+				return nil, false
+			}
+			node = n[idx].Value
+		case map[string]any:
+			value, has := n[segment]
+			if !has {
+				return nil, false
+			}
+			node = value
+		default:
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+func unescapePointer(segment string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+}
@@ -0,0 +1,64 @@
+package compilation_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/masnyjimmy/qapi/compilation"
+)
+
+const conflictingExternals = `info:
+  title: Externals
+  version: "1"
+servers:
+  - url: /
+externals:
+  a: a.yaml
+  b: b.yaml
+paths:
+  /a1:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json: <a:Money>
+  /b1:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json: <b:Money>
+  /c1:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json: <a:Money>
+  /d1:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json: <b:Money>
+`
+
+// external schemas are named in order of paths, so the same one keeps the
+// name and the same references are reported on every run
+func TestExternalNameConflict(t *testing.T) {
+	input := filepath.Join(writeFiles(t, map[string]string{
+		"api.qapi.yaml": conflictingExternals,
+		"a.yaml":        "components:\n  schemas:\n    Money:\n      type: integer\n",
+		"b.yaml":        "components:\n  schemas:\n    Money:\n      type: string\n",
+	}), "api.qapi.yaml")
+
+	conflict := "error: paths./%v.get.responses.200.application/json: external schemas a.yaml#/components/schemas/Money and b.yaml#/components/schemas/Money are both named Money\n"
+	want := "api.qapi.yaml:21:11: " + fmt.Sprintf(conflict, "b1") +
+		"api.qapi.yaml:33:11: " + fmt.Sprintf(conflict, "d1")
+
+	for range 10 {
+		if _, got := compileFile(t, input, compilation.Options{}); got != want {
+			t.Fatalf("got diagnostics\n%v\nwant\n%v", got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package compilation_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/loader"
+)
+
+var update = flag.Bool("update", false, "rewrite expected output of golden tests")
+
+// compileFile loads and compiles input the way qapi compile does,
+// diagnostics are located and written one per line with files relative to
+// directory of input
+func compileFile(t *testing.T, input string, opts compilation.Options) (*compilation.Document, string) {
+	t.Helper()
+
+	project, err := loader.Load(input)
+	if err != nil {
+		t.Fatalf("load %v: %v", input, err)
+	}
+
+	opts.Source = project.Source
+	opts.BaseDir = filepath.Dir(input)
+
+	var out compilation.Document
+	diagnostics, _ := compilation.Compile(&out, project.Document, opts)
+	project.Source.LocateAll(diagnostics)
+	diagnostics.Sort()
+
+	var sb strings.Builder
+	for _, d := range diagnostics {
+		if rel, err := filepath.Rel(opts.BaseDir, d.Pos.File); err == nil {
+			d.Pos.File = rel
+		}
+		sb.WriteString(d.String() + "\n")
+	}
+	return &out, sb.String()
+}
+
+// writeFiles writes files by name to temporary directory, which is returned
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// compileGolden compiles input in order to YAML, diagnostics fail the test
+func compileGolden(t *testing.T, input string, order compilation.Order) []byte {
+	t.Helper()
+
+	out, diagnostics := compileFile(t, input, compilation.Options{Order: order})
+	if diagnostics != "" {
+		t.Fatalf("compile %v: unexpected diagnostics:\n%v", input, diagnostics)
+	}
+
+	data, err := yaml.Marshal(*out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestCompileGolden compiles every testdata/*.qapi.yaml twice in both orders
+// and compares output byte by byte with name.openapi.yaml for sorted order
+// and name.source.openapi.yaml for source order. Run with -update to
+// rewrite them.
+func TestCompileGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.qapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs found in testdata")
+	}
+
+	orders := []struct {
+		name   string
+		order  compilation.Order
+		suffix string
+	}{
+		{"sorted", compilation.OrderSorted, ".openapi.yaml"},
+		{"source", compilation.OrderSource, ".source.openapi.yaml"},
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".qapi.yaml")
+
+		for _, order := range orders {
+			t.Run(name+"/"+order.name, func(t *testing.T) {
+				first := compileGolden(t, input, order.order)
+				second := compileGolden(t, input, order.order)
+
+				if !bytes.Equal(first, second) {
+					t.Fatalf("output differs between runs:\n%s\n---\n%s", first, second)
+				}
+
+				expectedFile := filepath.Join("testdata", name+order.suffix)
+
+				if *update {
+					if err := os.WriteFile(expectedFile, first, 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				expected, err := os.ReadFile(expectedFile)
+				if err != nil {
+					t.Fatalf("%v, run go test -update to create it", err)
+				}
+
+				if !bytes.Equal(first, expected) {
+					t.Errorf("output differs from %v, run go test -update to rewrite it:\n%s", expectedFile, first)
+				}
+			})
+		}
+	}
+}
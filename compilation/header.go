@@ -0,0 +1,14 @@
+package compilation
+
+type Header struct {
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool        `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      SchemaOrRef `json:"schema" yaml:"schema"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+}
+
+func (h Header) MarshalJSON() ([]byte, error) {
+	type header Header
+	return marshalExtendedJSON(header(h), h.Extensions)
+}
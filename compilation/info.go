@@ -1,7 +1,13 @@
 package compilation
 
 type Info struct {
-	Title       string `json:"title" yaml:"title"`
-	Version     string `json:"version" yaml:"version"`
-	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Title       string     `json:"title" yaml:"title"`
+	Version     string     `json:"version" yaml:"version"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	Extensions  Extensions `json:"-" yaml:",inline"`
+}
+
+func (i Info) MarshalJSON() ([]byte, error) {
+	type info Info
+	return marshalExtendedJSON(info(i), i.Extensions)
 }
@@ -0,0 +1,185 @@
+package compilation
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// JSONSchemaDialect is $schema of exported JSON Schema documents
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+const defsPrefix = "#/$defs/"
+
+// compileSchemas compiles schemas section of in only
+func compileSchemas(in *docs.Document, opts Options) (*CompileContext, error) {
+	ctx, err := newCompileContext(in, &Document{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.ParseSchemas()
+
+	return ctx, ctx.diagnostics.Err()
+}
+
+// jsonSchemaRefs rewrites references to $defs, references to root schema
+// point to the document itself, names of referenced schemas are collected
+func jsonSchemaRefs(root string, referenced map[string]bool) schemaMapper {
+	return func(s SchemaOrRef) SchemaOrRef {
+		ref, ok := s.GetRef()
+		if !ok {
+			return s
+		}
+
+		name, local := strings.CutPrefix(ref, componentsSchemaPrefix)
+		if !local {
+			return s
+		}
+		referenced[name] = true
+
+		if name == root {
+			return NewSchemaRef("#")
+		}
+		return NewSchemaRef(defsPrefix + name)
+	}
+}
+
+// marshalJSONSchema marshals schema as JSON Schema document, with $schema
+// and title before schema keywords and $defs after them
+func marshalJSONSchema(schema *SchemaOrRef, title string, defs Schemas) ([]byte, error) {
+	buf := bytes.NewBufferString(`{"$schema":`)
+
+	dialect, _ := json.Marshal(JSONSchemaDialect)
+	buf.Write(dialect)
+
+	if title != "" {
+		titleBytes, err := json.Marshal(title)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"title":`)
+		buf.Write(titleBytes)
+	}
+
+	if schema != nil {
+		body, err := json.Marshal(*schema)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(body) > 2 {
+			buf.WriteByte(',')
+			buf.Write(body[1 : len(body)-1])
+		}
+	}
+
+	if len(defs) != 0 {
+		defsBytes, err := json.Marshal(defs)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"$defs":`)
+		buf.Write(defsBytes)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// ExportJSONSchemas compiles every schema of in to self contained JSON
+// Schema 2020-12 document, schemas it references are put to its $defs
+func ExportJSONSchemas(in *docs.Document, opts Options) (map[string][]byte, docs.Diagnostics, error) {
+	ctx, err := compileSchemas(in, opts)
+	if err != nil {
+		return nil, ctxDiagnostics(ctx), err
+	}
+
+	schemas := ctx.out.Components.Schemas
+	out := make(map[string][]byte, len(in.Schemas))
+
+	// schemas bundled from external files are only put to $defs of the
+	// ones referencing them
+	for name := range in.Schemas {
+		schema := schemas[name]
+		referenced := make(map[string]bool)
+		rewrite := jsonSchemaRefs(name, referenced)
+
+		root := mapSchemaOrRef(NewSchemaDef(schema), rewrite)
+		defs := make(Schemas)
+
+		// referenced schemas are added until no new references are found
+		for len(referenced) != 0 {
+			var pending []string
+			for dep := range referenced {
+				if _, done := defs[dep]; !done && dep != name {
+					pending = append(pending, dep)
+				}
+			}
+			clear(referenced)
+
+			for _, dep := range pending {
+				depSchema, has := schemas[dep]
+				if !has {
+					ctx.report(docs.Errorf(docs.NodePath{"schemas", name}, "schema %v references undefined schema %v", name, dep))
+					continue
+				}
+
+				if mapped, ok := mapSchemaOrRef(NewSchemaDef(depSchema), rewrite).GetSchema(); ok {
+					defs[dep] = mapped
+				}
+			}
+		}
+
+		bytes, err := marshalJSONSchema(&root, name, defs)
+		if err != nil {
+			return nil, ctx.diagnostics, err
+		}
+		out[name] = bytes
+	}
+
+	if err := ctx.diagnostics.Err(); err != nil {
+		return nil, ctx.diagnostics, err
+	}
+
+	return out, ctx.diagnostics, nil
+}
+
+// ExportJSONSchemaBundle compiles schemas of in to single JSON Schema
+// 2020-12 document with all of them in $defs
+func ExportJSONSchemaBundle(in *docs.Document, opts Options) ([]byte, docs.Diagnostics, error) {
+	ctx, err := compileSchemas(in, opts)
+	if err != nil {
+		return nil, ctxDiagnostics(ctx), err
+	}
+
+	schemas := ctx.out.Components.Schemas
+	defs := make(Schemas, len(schemas))
+	referenced := make(map[string]bool)
+
+	for name, schema := range schemas {
+		if mapped, ok := mapSchemaOrRef(NewSchemaDef(schema), jsonSchemaRefs("", referenced)).GetSchema(); ok {
+			defs[name] = mapped
+		}
+	}
+
+	for dep := range referenced {
+		if _, has := schemas[dep]; !has {
+			ctx.report(docs.Errorf(docs.NodePath{"schemas"}, "undefined schema %v is referenced", dep))
+		}
+	}
+
+	if err := ctx.diagnostics.Err(); err != nil {
+		return nil, ctx.diagnostics, err
+	}
+
+	bytes, err := marshalJSONSchema(nil, in.Info.Title, defs)
+	if err != nil {
+		return nil, ctx.diagnostics, err
+	}
+
+	return bytes, ctx.diagnostics, nil
+}
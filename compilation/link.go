@@ -0,0 +1,8 @@
+package compilation
+
+type Link struct {
+	OperationId string         `json:"operationId" yaml:"operationId"`
+	Parameters  map[string]any `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody any            `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+}
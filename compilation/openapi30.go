@@ -0,0 +1,90 @@
+package compilation
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// downgradeTo30 rewrites compiled document to OpenAPI 3.0, constructs which
+// can't be represented are dropped with a warning naming the target, it's
+// also the first step of Swagger 2.0 conversion
+func (c *CompileContext) downgradeTo30(target Target) {
+	multipleExamples := false
+
+	c.out.mapSchemas(schemasOnly(func(s Schema) Schema {
+		if s.nullable {
+			s.nullable = false
+			s.Nullable = true
+		}
+
+		if len(s.Examples) != 0 {
+			multipleExamples = multipleExamples || len(s.Examples) > 1
+			example := s.Examples[0]
+			s.Example = &example
+			s.Examples = nil
+		}
+
+		return s
+	}))
+
+	if multipleExamples {
+		c.warn(nil, "%v schemas have single example, only the first one of examples is kept", target.name())
+	}
+
+	if len(c.out.Webhooks) != 0 {
+		c.warn(docs.NodePath{"webhooks"}, "webhooks are not supported by %v, they are dropped", target.name())
+		c.out.Webhooks = nil
+	}
+
+	var dropped []string
+
+	for _, name := range slices.Sorted(maps.Keys(c.out.Components.SecuritySchemes)) {
+		if c.out.Components.SecuritySchemes[name].Type == "mutualTLS" {
+			c.warn(docs.NodePath{"securitySchemes", name}, "mutualTLS security scheme %v is not supported by %v, it is dropped", name, target.name())
+			delete(c.out.Components.SecuritySchemes, name)
+			dropped = append(dropped, name)
+		}
+	}
+
+	if len(dropped) != 0 {
+		var dropRequirements func(op Operation) Operation
+		dropRequirements = func(op Operation) Operation {
+			op.Security = withoutSchemes(op.Security, dropped)
+
+			callbacks := make(map[string]Callback, len(op.Callbacks))
+			for name, callback := range op.Callbacks {
+				callbacks[name] = callback.mapOperations(dropRequirements)
+			}
+			if op.Callbacks != nil {
+				op.Callbacks = callbacks
+			}
+			return op
+		}
+		c.out.Paths = c.out.Paths.mapOperations(dropRequirements)
+	}
+}
+
+// withoutSchemes removes dropped schemes from requirements, requirements
+// left empty are removed too
+func withoutSchemes(requirements []SecurityRequirement, dropped []string) []SecurityRequirement {
+	if requirements == nil {
+		return nil
+	}
+
+	out := make([]SecurityRequirement, 0, len(requirements))
+
+	for _, requirement := range requirements {
+		kept := maps.Clone(requirement)
+		for _, name := range dropped {
+			delete(kept, name)
+		}
+
+		if len(kept) != 0 || len(requirement) == 0 {
+			out = append(out, kept)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,313 @@
+package compilation
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// Casing of generated operation ids
+type Casing string
+
+const (
+	// CasingCamel generates ids like getUserById, it's the default
+	CasingCamel  Casing = "camel"
+	CasingPascal Casing = "pascal"
+	CasingSnake  Casing = "snake"
+	CasingKebab  Casing = "kebab"
+)
+
+func ParseCasing(s string) (Casing, error) {
+	switch c := Casing(s); c {
+	case CasingCamel, CasingPascal, CasingSnake, CasingKebab:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown casing %q, expected %v, %v, %v or %v", s, CasingCamel, CasingPascal, CasingSnake, CasingKebab)
+	}
+}
+
+// DefaultIdTemplate is used when Options.IdTemplate is empty, {method} is
+// replaced by method of operation and {path} by words of its path
+const DefaultIdTemplate = "{method} {path}"
+
+var idPlaceholder = regexp.MustCompile(`\{(\w*)\}`)
+
+// CheckIdTemplate reports unknown placeholders of template
+func CheckIdTemplate(template string) error {
+	for _, match := range idPlaceholder.FindAllStringSubmatch(template, -1) {
+		if match[1] != "method" && match[1] != "path" {
+			return fmt.Errorf("unknown placeholder %v in id template %q, expected {method} or {path}", match[0], template)
+		}
+	}
+	return nil
+}
+
+// splitWords splits s on characters other than letters and digits and on
+// lower to upper case changes, e.g. user-ID or userId
+func splitWords(s string) []string {
+	var (
+		out  []string
+		word []rune
+	)
+
+	flush := func() {
+		if len(word) != 0 {
+			out = append(out, string(word))
+			word = word[:0]
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) != 0 && !unicode.IsUpper(word[len(word)-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	return out
+}
+
+// irregular plurals of collection names
+var singulars = map[string]string{
+	"people":   "person",
+	"children": "child",
+	"men":      "man",
+	"women":    "woman",
+	"data":     "data",
+	"media":    "media",
+	"series":   "series",
+	"news":     "news",
+}
+
+// singular guesses singular form of plural word, e.g. users or categories
+func singular(word string) string {
+	lower := strings.ToLower(word)
+
+	if s, has := singulars[lower]; has {
+		return s
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss"):
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
+
+func isParamSegment(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// pathWords describes path in words, collections followed by path params
+// are singular and params are prefixed by "by", e.g. /users/{id} is user by
+// id
+func pathWords(path string) []string {
+	segments := slices.DeleteFunc(strings.Split(path, "/"), func(s string) bool {
+		return s == ""
+	})
+
+	var out []string
+	for i, segment := range segments {
+		if isParamSegment(segment) {
+			if i != 0 && isParamSegment(segments[i-1]) {
+				out = append(out, "and")
+			} else {
+				out = append(out, "by")
+			}
+			out = append(out, splitWords(segment)...)
+			continue
+		}
+
+		words := splitWords(segment)
+		if i+1 < len(segments) && isParamSegment(segments[i+1]) && len(words) != 0 {
+			words[len(words)-1] = singular(words[len(words)-1])
+		}
+		out = append(out, words...)
+	}
+
+	return out
+}
+
+func capitalize(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// joinWords joins words in casing
+func joinWords(words []string, casing Casing) string {
+	lower := make([]string, len(words))
+	for i, word := range words {
+		lower[i] = strings.ToLower(word)
+	}
+
+	switch casing {
+	case CasingSnake:
+		return strings.Join(lower, "_")
+	case CasingKebab:
+		return strings.Join(lower, "-")
+	}
+
+	var sb strings.Builder
+	for i, word := range lower {
+		if i == 0 && casing != CasingPascal {
+			sb.WriteString(word)
+		} else {
+			sb.WriteString(capitalize(word))
+		}
+	}
+	return sb.String()
+}
+
+// GenerateOperationId returns id of operation of method and path generated
+// by template in casing, empty template is DefaultIdTemplate and empty
+// casing is CasingCamel, e.g. get /users/{id} is getUserById
+func GenerateOperationId(method, path, template string, casing Casing) string {
+	if template == "" {
+		template = DefaultIdTemplate
+	}
+
+	expanded := idPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{method}":
+			return " " + method + " "
+		case "{path}":
+			return " " + strings.Join(pathWords(path), " ") + " "
+		default:
+			return placeholder
+		}
+	})
+
+	return joinWords(splitWords(expanded), casing)
+}
+
+// generateOperationIds sets ids of operations of paths and webhooks
+// without one, ids of callbacks' operations are not generated
+func (c *CompileContext) generateOperationIds() {
+	generate := func(paths Paths) {
+		for _, key := range slices.Sorted(maps.Keys(paths)) {
+			item := paths[key]
+
+			for method, op := range item.operations() {
+				if op.OperationId != "" {
+					continue
+				}
+
+				op.OperationId = GenerateOperationId(method, key, c.idTemplate, c.idCasing)
+				op.generatedId = true
+
+				if _, has := c.operations[op.OperationId]; !has {
+					c.operations[op.OperationId] = op
+				}
+			}
+		}
+	}
+
+	generate(c.out.Paths)
+	generate(c.out.Webhooks)
+}
+
+// operations returns operations of path item by method
+func (p Path) operations() func(yield func(string, *Operation) bool) {
+	return func(yield func(string, *Operation) bool) {
+		for _, entry := range []struct {
+			method string
+			op     *Operation
+		}{{"get", p.Get}, {"post", p.Post}, {"put", p.Put}, {"patch", p.Patch}, {"delete", p.Delete}} {
+			if entry.op != nil && !yield(entry.method, entry.op) {
+				return
+			}
+		}
+	}
+}
+
+// allOperations returns operations of paths, webhooks and their callbacks
+func allOperations(paths Paths) []*Operation {
+	var out []*Operation
+	for _, item := range paths {
+		for _, op := range item.operations() {
+			out = append(out, op)
+			for _, callback := range op.Callbacks {
+				out = append(out, allOperations(callback)...)
+			}
+		}
+	}
+	return out
+}
+
+// compareDeclared orders operations by their declaration, root file of the
+// source first, by node path when source isn't given
+func (c *CompileContext) compareDeclared(a, b *Operation) int {
+	if c.declared != nil {
+		files := c.declared.Files()
+		pa, pb := c.declared.Position(a.at), c.declared.Position(b.at)
+
+		if pa.IsValid() && pb.IsValid() {
+			if order := cmp.Or(
+				cmp.Compare(slices.Index(files, pa.File), slices.Index(files, pb.File)),
+				cmp.Compare(pa.Line, pb.Line),
+				cmp.Compare(pa.Column, pb.Column),
+			); order != 0 {
+				return order
+			}
+		}
+	}
+
+	return strings.Compare(a.at.String(), b.at.String())
+}
+
+// checkOperationIds reports operations sharing id, the first explicit one
+// by declaration keeps it and links refer to it
+func (c *CompileContext) checkOperationIds() {
+	byId := make(map[string][]*Operation)
+
+	for _, op := range slices.Concat(allOperations(c.out.Paths), allOperations(c.out.Webhooks)) {
+		if op.OperationId != "" {
+			byId[op.OperationId] = append(byId[op.OperationId], op)
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(byId)) {
+		ops := byId[id]
+		if len(ops) < 2 {
+			continue
+		}
+
+		// explicit ids take precedence over generated ones
+		slices.SortFunc(ops, func(a, b *Operation) int {
+			if a.generatedId != b.generatedId {
+				if b.generatedId {
+					return -1
+				}
+				return 1
+			}
+			return c.compareDeclared(a, b)
+		})
+
+		c.operations[id] = ops[0]
+
+		for _, op := range ops[1:] {
+			if op.generatedId {
+				c.report(docs.Errorf(op.at, "generated operation id %v is already used by %v, set id explicitly", id, ops[0].at))
+			} else {
+				c.report(docs.Errorf(slices.Concat(op.at, docs.NodePath{"id"}), "operation id %v is already used by %v", id, ops[0].at))
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package compilation_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/masnyjimmy/qapi/compilation"
+)
+
+func TestGenerateOperationId(t *testing.T) {
+	tests := []struct {
+		method, path, template string
+		casing                 compilation.Casing
+		want                   string
+	}{
+		{"get", "/users", "", "", "getUsers"},
+		{"get", "/users/{id}", "", "", "getUserById"},
+		{"delete", "/categories/{categoryId}/addresses/{addressId}", "", "", "deleteCategoryByCategoryIdAddressByAddressId"},
+		{"post", "newUser", "", "", "postNewUser"},
+		{"get", "/users/{id}", "api_{method}_{path}", compilation.CasingSnake, "api_get_user_by_id"},
+		{"get", "/users/{id}", "", compilation.CasingPascal, "GetUserById"},
+		{"get", "/users/{id}", "", compilation.CasingKebab, "get-user-by-id"},
+		{"get", "/maps/{x}/{y}", "", "", "getMapByXAndY"},
+		{"get", "/people/{id}", "", "", "getPersonById"},
+		{"get", "/order-items/{itemId}", "", "", "getOrderItemByItemId"},
+		{"get", "/", "", "", "get"},
+	}
+
+	for _, test := range tests {
+		got := compilation.GenerateOperationId(test.method, test.path, test.template, test.casing)
+		if got != test.want {
+			t.Errorf("GenerateOperationId(%q, %q, %q, %q) = %q, want %q", test.method, test.path, test.template, test.casing, got, test.want)
+		}
+	}
+}
+
+func TestCheckIdTemplate(t *testing.T) {
+	if err := compilation.CheckIdTemplate("{method}_{path}"); err != nil {
+		t.Errorf("valid template: %v", err)
+	}
+	if err := compilation.CheckIdTemplate("{verb}{path}"); err == nil {
+		t.Error("unknown placeholder {verb} is not reported")
+	}
+}
+
+const duplicateIds = `info:
+  title: Ids
+  version: "1"
+servers:
+  - url: /
+paths:
+  /users:
+    get:
+      id: list_users
+      responses:
+        200:
+          description: ok
+  /accounts:
+    get:
+      id: list_users
+      responses:
+        200:
+          description: ok
+  /getStatus:
+    get:
+      responses:
+        200:
+          description: ok
+  /status:
+    get:
+      id: getGetStatus
+      responses:
+        200:
+          description: ok
+`
+
+func TestDuplicateOperationIds(t *testing.T) {
+	input := filepath.Join(writeFiles(t, map[string]string{"api.qapi.yaml": duplicateIds}), "api.qapi.yaml")
+
+	for _, order := range []compilation.Order{compilation.OrderSorted, compilation.OrderSource} {
+		_, got := compileFile(t, input, compilation.Options{Order: order})
+
+		want := "api.qapi.yaml:15:7: error: paths./accounts.get.id: operation id list_users is already used by paths./users.get\n"
+		if got != want {
+			t.Errorf("order %v: got diagnostics\n%v\nwant\n%v", order, got, want)
+		}
+	}
+}
+
+func TestGeneratedDuplicateOperationIds(t *testing.T) {
+	input := filepath.Join(writeFiles(t, map[string]string{"api.qapi.yaml": duplicateIds}), "api.qapi.yaml")
+
+	out, got := compileFile(t, input, compilation.Options{GenerateIds: true})
+
+	want := "api.qapi.yaml:15:7: error: paths./accounts.get.id: operation id list_users is already used by paths./users.get\n" +
+		"api.qapi.yaml:20:5: error: paths./getStatus.get: generated operation id getGetStatus is already used by paths./status.get, set id explicitly\n"
+	if got != want {
+		t.Errorf("got diagnostics\n%v\nwant\n%v", got, want)
+	}
+
+	if id := out.Paths["/status"].Get.OperationId; id != "getGetStatus" {
+		t.Errorf("explicit id of /status is %q, want getGetStatus", id)
+	}
+}
@@ -0,0 +1,118 @@
+package compilation
+
+import (
+	"fmt"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// Order of paths, schemas and responses in compiled document
+type Order int
+
+const (
+	// OrderSorted sorts keys, it's the default
+	OrderSorted Order = iota
+	// OrderSource keeps order of qapi source, requires Options.Source
+	OrderSource
+)
+
+// Target is format of compiled document
+type Target string
+
+const (
+	TargetOpenAPI31 Target = "openapi3.1"
+	// TargetOpenAPI30 emits OpenAPI 3.0.3, constructs of 3.1 are downgraded
+	// where possible, the rest is dropped with a warning
+	TargetOpenAPI30 Target = "openapi3.0"
+	// TargetSwagger2 emits Swagger 2.0, see CompileSwagger2
+	TargetSwagger2 Target = "swagger2"
+)
+
+// name of target format used in diagnostics
+func (t Target) name() string {
+	switch t {
+	case TargetOpenAPI30:
+		return "OpenAPI 3.0"
+	case TargetSwagger2:
+		return "Swagger 2.0"
+	default:
+		return "OpenAPI 3.1"
+	}
+}
+
+// version of OpenAPI written to compiled document
+func (t Target) version() string {
+	switch t {
+	case TargetOpenAPI30:
+		return "3.0.3"
+	default:
+		return "3.1.0"
+	}
+}
+
+func ParseTarget(s string) (Target, error) {
+	switch t := Target(s); t {
+	case TargetOpenAPI31, TargetOpenAPI30, TargetSwagger2:
+		return t, nil
+	default:
+		return "", fmt.Errorf("unknown target %q, expected %v, %v or %v", s, TargetOpenAPI31, TargetOpenAPI30, TargetSwagger2)
+	}
+}
+
+// ExternalRefs selects how references to schemas of external files, e.g.
+// <common.yaml#Money>, are compiled
+type ExternalRefs string
+
+const (
+	// ExternalBundle copies referenced schemas to components, it's the
+	// default
+	ExternalBundle ExternalRefs = "bundle"
+	// ExternalRef emits references relative to the qapi document
+	ExternalRef ExternalRefs = "ref"
+)
+
+func ParseExternalRefs(s string) (ExternalRefs, error) {
+	switch e := ExternalRefs(s); e {
+	case ExternalBundle, ExternalRef:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown external refs mode %q, expected %v or %v", s, ExternalBundle, ExternalRef)
+	}
+}
+
+// Options of compilation, zero value is valid
+type Options struct {
+	// empty target is TargetOpenAPI31
+	Target Target
+	Order  Order
+	// source of compiled document, used to locate nodes
+	Source *docs.Source
+	// empty mode is ExternalBundle
+	ExternalRefs ExternalRefs
+	// directory external files are relative to, working directory when empty
+	BaseDir string
+	// directory compiled document is written to, references of ExternalRef
+	// are relative to it, BaseDir when empty
+	OutputDir string
+	// drop component schemas no operation, webhook or default response
+	// references
+	Prune bool
+	// generate ids of operations of paths and webhooks without one, e.g.
+	// getUserById for get /users/{id}
+	GenerateIds bool
+	// template of generated ids, empty template is DefaultIdTemplate
+	IdTemplate string
+	// casing of generated ids, empty casing is CasingCamel
+	IdCasing Casing
+}
+
+func ParseOrder(s string) (Order, error) {
+	switch s {
+	case "sorted":
+		return OrderSorted, nil
+	case "source":
+		return OrderSource, nil
+	default:
+		return 0, fmt.Errorf("unknown order %q, expected sorted or source", s)
+	}
+}
@@ -0,0 +1,127 @@
+package compilation
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+)
+
+// nodePos is position of qapi node used to order output, file is index of
+// the file in docs.Source.Files, so the root file comes first and imported
+// or mounted ones follow in order of their declaration
+type nodePos struct {
+	file, line, column int
+}
+
+func (p nodePos) isValid() bool {
+	return p.line > 0
+}
+
+// positioned values remember position of their qapi node, it's set only
+// when compiling with OrderSource
+type positioned interface {
+	sourcePos() nodePos
+}
+
+func comparePositions(a, b nodePos) int {
+	switch {
+	case a.isValid() && !b.isValid():
+		return -1
+	case !a.isValid() && b.isValid():
+		return 1
+	case !a.isValid():
+		return 0
+	}
+
+	return cmp.Or(
+		cmp.Compare(a.file, b.file),
+		cmp.Compare(a.line, b.line),
+		cmp.Compare(a.column, b.column),
+	)
+}
+
+// orderedKeys returns keys of values with position in source order, followed
+// by sorted keys of the rest
+func orderedKeys[K cmp.Ordered, V positioned](m map[K]V) []K {
+	keys := slices.Sorted(maps.Keys(m))
+
+	slices.SortStableFunc(keys, func(a, b K) int {
+		return comparePositions(m[a].sourcePos(), m[b].sourcePos())
+	})
+
+	return keys
+}
+
+func marshalOrderedJSON[K cmp.Ordered, V positioned](m map[K]V) ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	buf.WriteByte('{')
+
+	for idx, key := range orderedKeys(m) {
+		bytes, err := json.Marshal(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(bytes)
+
+		buf.WriteByte(':')
+
+		bytes, err = json.Marshal(m[key])
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(bytes)
+
+		if idx != (len(m) - 1) {
+			buf.WriteByte(',')
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func marshalOrderedYAML[K cmp.Ordered, V positioned](m map[K]V) (any, error) {
+	s := make(yaml.MapSlice, 0, len(m))
+
+	for _, key := range orderedKeys(m) {
+		s = append(s, yaml.MapItem{
+			Key:   key,
+			Value: m[key],
+		})
+	}
+
+	return s, nil
+}
+
+type Paths map[string]Path
+
+func (p Paths) MarshalJSON() ([]byte, error) { return marshalOrderedJSON(p) }
+func (p Paths) MarshalYAML() (any, error)    { return marshalOrderedYAML(p) }
+
+type Schemas map[string]Schema
+
+func (s Schemas) MarshalJSON() ([]byte, error) { return marshalOrderedJSON(s) }
+func (s Schemas) MarshalYAML() (any, error)    { return marshalOrderedYAML(s) }
+
+type Responses map[StatusCode]Response
+
+func (r Responses) MarshalJSON() ([]byte, error) { return marshalOrderedJSON(r) }
+func (r Responses) MarshalYAML() (any, error)    { return marshalOrderedYAML(r) }
+
+func (p Path) sourcePos() nodePos     { return p.pos }
+func (s Schema) sourcePos() nodePos   { return s.pos }
+func (r Response) sourcePos() nodePos { return r.pos }
@@ -13,4 +13,11 @@ type Parameter struct {
 	In       ParamIn     `json:"in" yaml:"in"`
 	Required bool        `json:"required" yaml:"required"`
 	Schema   SchemaOrRef `json:"schema" yaml:"schema"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+}
+
+func (p Parameter) MarshalJSON() ([]byte, error) {
+	type parameter Parameter
+	return marshalExtendedJSON(parameter(p), p.Extensions)
 }
@@ -7,4 +7,16 @@ type Path struct {
 	Put     *Operation `json:"put,omitempty" yaml:"put,omitempty"`
 	Patch   *Operation `json:"patch,omitempty" yaml:"patch,omitempty"`
 	Delete  *Operation `json:"delete,omitempty" yaml:"delete,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+
+	pos nodePos
 }
+
+func (p Path) MarshalJSON() ([]byte, error) {
+	type path Path
+	return marshalExtendedJSON(path(p), p.Extensions)
+}
+
+// Callback maps runtime expression (e.g. {$request.body#/callbackUrl}) to path item
+type Callback = Paths
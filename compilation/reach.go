@@ -0,0 +1,110 @@
+package compilation
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// collectRefs returns mapper recording names of referenced component
+// schemas in refs, schemas are left unchanged
+func collectRefs(refs map[string]bool) schemaMapper {
+	return func(s SchemaOrRef) SchemaOrRef {
+		if ref, ok := s.GetRef(); ok {
+			if name, ok := strings.CutPrefix(ref, componentsSchemaPrefix); ok {
+				refs[name] = true
+			}
+		}
+		return s
+	}
+}
+
+// ReachableSchemas returns names of component schemas referenced by
+// operations of paths, webhooks and their callbacks, directly or through
+// other schemas
+func (d *Document) ReachableSchemas() map[string]bool {
+	return d.reachableSchemas(nil)
+}
+
+// reachableSchemas returns names of component schemas reachable from
+// operations and roots
+func (d *Document) reachableSchemas(roots []Response) map[string]bool {
+	refs := make(map[string]bool)
+	collect := collectRefs(refs)
+
+	collectOperation := func(op Operation) Operation {
+		return op.mapSchemas(collect)
+	}
+	d.Paths.mapOperations(collectOperation)
+	d.Webhooks.mapOperations(collectOperation)
+
+	for _, response := range roots {
+		mapContent(response.Content, collect)
+		mapHeaders(response.Headers, collect)
+	}
+
+	pending := slices.Collect(maps.Keys(refs))
+	for len(pending) != 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		schema, has := d.Components.Schemas[name]
+		if !has {
+			continue
+		}
+
+		nested := make(map[string]bool)
+		mapSchemaOrRef(NewSchemaDef(schema), collectRefs(nested))
+
+		for ref := range nested {
+			if !refs[ref] {
+				refs[ref] = true
+				pending = append(pending, ref)
+			}
+		}
+	}
+
+	return refs
+}
+
+// checkReachability warns about schemas no operation, webhook or default
+// response references and traits no method applies. With prune unreferenced
+// schemas are dropped from components.
+func (c *CompileContext) checkReachability() {
+	// operations which failed to compile don't reference anything
+	if c.diagnostics.HasErrors() {
+		return
+	}
+
+	var defaults []Response
+	for _, group := range c.defaultResponses {
+		defaults = slices.AppendSeq(defaults, maps.Values(group))
+	}
+
+	reached := c.out.reachableSchemas(defaults)
+
+	for _, name := range slices.Sorted(maps.Keys(c.in.Schemas)) {
+		if !reached[name] {
+			c.warn(docs.NodePath{"schemas", name}, "schema %v is not used by any operation, webhook or default response", name)
+		}
+	}
+
+	for _, expr := range slices.Sorted(maps.Keys(c.in.Traits)) {
+		groups := traitDefExpr.FindStringSubmatch(expr)
+		if groups == nil || c.failedTraits[groups[1]] {
+			continue
+		}
+
+		if !c.usedTraits[groups[1]] {
+			c.warn(docs.NodePath{"traits", expr}, "trait %v is not applied to any method", groups[1])
+		}
+	}
+
+	if c.prune {
+		maps.DeleteFunc(c.out.Components.Schemas, func(name string, _ Schema) bool {
+			return !reached[name]
+		})
+	}
+}
@@ -3,6 +3,17 @@ package compilation
 type Response struct {
 	Description string                 `json:"description,omitempty" yaml:"description,omitempty"`
 	Content     map[string]TypedSchema `json:"content,omitempty" yaml:"content,omitempty"`
+	Headers     map[string]Header      `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Links       map[string]Link        `json:"links,omitempty" yaml:"links,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+
+	pos nodePos
+}
+
+func (r Response) MarshalJSON() ([]byte, error) {
+	type response Response
+	return marshalExtendedJSON(response(r), r.Extensions)
 }
 
 type StatusCode = string
@@ -28,13 +28,16 @@ type Property struct {
 type Properties []Property
 
 type Schema struct {
-	Type SchemaType `json:"type" yaml:"type"`
+	Type SchemaType `json:"type,omitempty" yaml:"type,omitempty"`
 
 	Properties Properties   `json:"properties,omitempty" yaml:"properties,omitempty"`
 	Items      *SchemaOrRef `json:"items,omitempty" yaml:"items,omitempty"`
 
 	nullable bool // `json:"nullable,omitempty" yaml:"nullable,omitempty"`
 
+	// OpenAPI 3.0 only, set by downgrade instead of nullable
+	Nullable bool `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+
 	Default *any `json:"default,omitempty" yaml:"default,omitempty"`
 
 	Required []string `json:"required,omitempty" yaml:"required,omitempty"`
@@ -53,6 +56,17 @@ type Schema struct {
 	MaxItems *uint `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
 
 	Examples []any `json:"examples,omitempty" yaml:"examples,omitempty"`
+	// OpenAPI 3.0 only, set by downgrade instead of examples
+	Example *any `json:"example,omitempty" yaml:"example,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+
+	pos nodePos
+}
+
+func (t Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	return marshalExtendedJSON(schema(t), t.Extensions)
 }
 
 type SchemaOrRef struct {
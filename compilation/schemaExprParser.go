@@ -8,7 +8,9 @@ import (
 	"strings"
 )
 
-var schemaExprRegex = regexp.MustCompile(`^(?:((?:boolean|string|integer|number)\??)(?:\((.*)\))?|(?:<(\w+)>))(\??\[[^\]]*\])*$`)
+// references are local <Name>, to file <common.yaml#Money>, also with JSON
+// pointer <common.yaml#/$defs/Money>, or through external alias <shared:Money>
+var schemaExprRegex = regexp.MustCompile(`^(?:((?:boolean|string|integer|number)\??)(?:\((.*)\))?|(?:<(\w+|\w+:\w+|[\w.\-/]+#[\w.\-/$~]+)>))(\??\[[^\]]*\])*$`)
 
 func extractBetween(s string, left, right string) (string, bool) {
 	s, ok := strings.CutPrefix(s, left)
@@ -285,9 +287,13 @@ func parseSchema(expr string) (SchemaOrRef, error) {
 
 	var out SchemaOrRef
 
-	if ref != "" {
+	switch {
+	case strings.ContainsAny(ref, "#:"):
+		// resolved by CompileContext.resolveExternalRefs
+		out = NewSchemaRef(ref)
+	case ref != "":
 		out = NewSchemaRef(fmt.Sprintf("#/components/schemas/%s", ref))
-	} else {
+	default:
 		schema, err := parseObjectSchema(baseType, params)
 		if err != nil {
 			return SchemaOrRef{}, err
@@ -308,13 +314,15 @@ func parseSchema(expr string) (SchemaOrRef, error) {
 }
 
 func ParseSchemaWithContext(expr string, context map[string]string) (SchemaOrRef, error) {
-	oldnew := make([]string, 0, len(context)*2)
-	for old, new := range context {
-		oldnew = append(oldnew, "#"+old, new)
+	replacer := &argsReplacer{
+		values: context,
 	}
 
-	replacer := strings.NewReplacer(oldnew...)
 	expr = replacer.Replace(expr)
 
+	if replacer.err != nil {
+		return SchemaOrRef{}, replacer.err
+	}
+
 	return parseSchema(expr)
 }
@@ -0,0 +1,14 @@
+package compilation
+
+type SecurityScheme struct {
+	Type             string         `json:"type" yaml:"type"`
+	Description      string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Name             string         `json:"name,omitempty" yaml:"name,omitempty"`
+	In               string         `json:"in,omitempty" yaml:"in,omitempty"`
+	Scheme           string         `json:"scheme,omitempty" yaml:"scheme,omitempty"`
+	BearerFormat     string         `json:"bearerFormat,omitempty" yaml:"bearerFormat,omitempty"`
+	Flows            map[string]any `json:"flows,omitempty" yaml:"flows,omitempty"`
+	OpenIdConnectUrl string         `json:"openIdConnectUrl,omitempty" yaml:"openIdConnectUrl,omitempty"`
+}
+
+type SecurityRequirement = map[string][]string
@@ -1,6 +1,12 @@
 package compilation
 
 type Server struct {
-	Url         string `json:"url"`
-	Description string `json:"description"`
+	Url         string     `json:"url"`
+	Description string     `json:"description"`
+	Extensions  Extensions `json:"-" yaml:",inline"`
+}
+
+func (s Server) MarshalJSON() ([]byte, error) {
+	type server Server
+	return marshalExtendedJSON(server(s), s.Extensions)
 }
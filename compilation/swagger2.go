@@ -0,0 +1,542 @@
+package compilation
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// Swagger 2.0 model, converted from compiled OpenAPI document
+
+type Swagger2Document struct {
+	Swagger             string                            `json:"swagger" yaml:"swagger"`
+	Info                Info                              `json:"info" yaml:"info"`
+	Host                string                            `json:"host,omitempty" yaml:"host,omitempty"`
+	BasePath            string                            `json:"basePath,omitempty" yaml:"basePath,omitempty"`
+	Schemes             []string                          `json:"schemes,omitempty" yaml:"schemes,omitempty"`
+	Tags                Tags                              `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Paths               Swagger2Paths                     `json:"paths" yaml:"paths"`
+	Definitions         Schemas                           `json:"definitions,omitempty" yaml:"definitions,omitempty"`
+	SecurityDefinitions map[string]Swagger2SecurityScheme `json:"securityDefinitions,omitempty" yaml:"securityDefinitions,omitempty"`
+}
+
+type Swagger2PathItem struct {
+	Get    *Swagger2Operation `json:"get,omitempty" yaml:"get,omitempty"`
+	Post   *Swagger2Operation `json:"post,omitempty" yaml:"post,omitempty"`
+	Put    *Swagger2Operation `json:"put,omitempty" yaml:"put,omitempty"`
+	Patch  *Swagger2Operation `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Delete *Swagger2Operation `json:"delete,omitempty" yaml:"delete,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+
+	pos nodePos
+}
+
+func (p Swagger2PathItem) MarshalJSON() ([]byte, error) {
+	type pathItem Swagger2PathItem
+	return marshalExtendedJSON(pathItem(p), p.Extensions)
+}
+
+type Swagger2Paths map[string]Swagger2PathItem
+
+func (p Swagger2Paths) MarshalJSON() ([]byte, error) { return marshalOrderedJSON(p) }
+func (p Swagger2Paths) MarshalYAML() (any, error)    { return marshalOrderedYAML(p) }
+
+type Swagger2Operation struct {
+	Summary     string                `json:"summary,omitempty" yaml:"summary,omitempty"`
+	OperationId string                `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Tags        []string              `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Consumes    []string              `json:"consumes,omitempty" yaml:"consumes,omitempty"`
+	Produces    []string              `json:"produces,omitempty" yaml:"produces,omitempty"`
+	Parameters  []Swagger2Parameter   `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Responses   Swagger2Responses     `json:"responses" yaml:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty" yaml:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+}
+
+func (o Swagger2Operation) MarshalJSON() ([]byte, error) {
+	type operation Swagger2Operation
+	return marshalExtendedJSON(operation(o), o.Extensions)
+}
+
+// Swagger2Items is type of non-body parameters, headers and their items
+type Swagger2Items struct {
+	Type   SchemaType     `json:"type,omitempty" yaml:"type,omitempty"`
+	Format string         `json:"format,omitempty" yaml:"format,omitempty"`
+	Items  *Swagger2Items `json:"items,omitempty" yaml:"items,omitempty"`
+
+	Default *any `json:"default,omitempty" yaml:"default,omitempty"`
+
+	Minimum *int `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum *int `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+
+	MinLength *uint `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength *uint `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+
+	MinItems    *uint `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems    *uint `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+	UniqueItems bool  `json:"uniqueItems,omitempty" yaml:"uniqueItems,omitempty"`
+}
+
+type Swagger2Parameter struct {
+	Name     string `json:"name" yaml:"name"`
+	In       string `json:"in" yaml:"in"`
+	Required bool   `json:"required" yaml:"required"`
+	// body parameters only
+	Schema *SchemaOrRef `json:"schema,omitempty" yaml:"schema,omitempty"`
+
+	Swagger2Items `yaml:",inline"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+}
+
+func (p Swagger2Parameter) MarshalJSON() ([]byte, error) {
+	type parameter Swagger2Parameter
+	return marshalExtendedJSON(parameter(p), p.Extensions)
+}
+
+type Swagger2Header struct {
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	Swagger2Items `yaml:",inline"`
+}
+
+type Swagger2Response struct {
+	Description string                    `json:"description" yaml:"description"`
+	Schema      *SchemaOrRef              `json:"schema,omitempty" yaml:"schema,omitempty"`
+	Headers     map[string]Swagger2Header `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	Extensions Extensions `json:"-" yaml:",inline"`
+
+	pos nodePos
+}
+
+func (r Swagger2Response) MarshalJSON() ([]byte, error) {
+	type response Swagger2Response
+	return marshalExtendedJSON(response(r), r.Extensions)
+}
+
+type Swagger2Responses map[StatusCode]Swagger2Response
+
+func (r Swagger2Responses) MarshalJSON() ([]byte, error) { return marshalOrderedJSON(r) }
+func (r Swagger2Responses) MarshalYAML() (any, error)    { return marshalOrderedYAML(r) }
+
+func (p Swagger2PathItem) sourcePos() nodePos { return p.pos }
+func (r Swagger2Response) sourcePos() nodePos { return r.pos }
+
+type Swagger2SecurityScheme struct {
+	Type             string            `json:"type" yaml:"type"`
+	Description      string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Name             string            `json:"name,omitempty" yaml:"name,omitempty"`
+	In               string            `json:"in,omitempty" yaml:"in,omitempty"`
+	Flow             string            `json:"flow,omitempty" yaml:"flow,omitempty"`
+	AuthorizationUrl string            `json:"authorizationUrl,omitempty" yaml:"authorizationUrl,omitempty"`
+	TokenUrl         string            `json:"tokenUrl,omitempty" yaml:"tokenUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+const (
+	componentsSchemaPrefix = "#/components/schemas/"
+	definitionsPrefix      = "#/definitions/"
+)
+
+var formMediaTypes = []string{"multipart/form-data", "application/x-www-form-urlencoded"}
+
+// swagger2 flows of OpenAPI oauth2 flows
+var swagger2Flows = map[string]string{
+	"implicit":          "implicit",
+	"password":          "password",
+	"clientCredentials": "application",
+	"authorizationCode": "accessCode",
+}
+
+// swagger2Schema rewrites references to definitions and nullable to
+// x-nullable extension
+var swagger2Schema schemaMapper = func(s SchemaOrRef) SchemaOrRef {
+	if ref, ok := s.GetRef(); ok {
+		if name, local := strings.CutPrefix(ref, componentsSchemaPrefix); local {
+			return NewSchemaRef(definitionsPrefix + name)
+		}
+		return s
+	}
+
+	schema, ok := s.GetSchema()
+	if !ok {
+		return s
+	}
+
+	if schema.Nullable {
+		schema.Nullable = false
+		schema.Extensions = maps.Clone(schema.Extensions)
+		if schema.Extensions == nil {
+			schema.Extensions = make(Extensions)
+		}
+		schema.Extensions["x-nullable"] = true
+	}
+	return NewSchemaDef(schema)
+}
+
+// toSwagger2 converts compiled document, already downgraded to OpenAPI 3.0
+func (c *CompileContext) toSwagger2() Swagger2Document {
+	out := Swagger2Document{
+		Swagger: "2.0",
+		Info:    c.out.Info,
+		Tags:    c.out.Tags,
+		Paths:   make(Swagger2Paths, len(c.out.Paths)),
+	}
+
+	c.swagger2Servers(&out)
+
+	dropped := c.swagger2SecurityDefinitions(&out)
+
+	if c.out.Components.Schemas != nil {
+		out.Definitions = make(Schemas, len(c.out.Components.Schemas))
+		for name, schema := range c.out.Components.Schemas {
+			if mapped, ok := mapSchemaOrRef(NewSchemaDef(schema), swagger2Schema).GetSchema(); ok {
+				out.Definitions[name] = mapped
+			}
+		}
+	}
+
+	for key, item := range c.out.Paths {
+		outItem := Swagger2PathItem{
+			Extensions: item.Extensions,
+			pos:        item.pos,
+		}
+
+		for _, op := range []struct {
+			in  *Operation
+			out **Swagger2Operation
+		}{
+			{item.Get, &outItem.Get},
+			{item.Post, &outItem.Post},
+			{item.Put, &outItem.Put},
+			{item.Patch, &outItem.Patch},
+			{item.Delete, &outItem.Delete},
+		} {
+			if op.in != nil {
+				converted := c.swagger2Operation(*op.in, dropped)
+				*op.out = &converted
+			}
+		}
+
+		out.Paths[key] = outItem
+	}
+
+	return out
+}
+
+// swagger2Servers derives host, base path and schemes from servers
+func (c *CompileContext) swagger2Servers(out *Swagger2Document) {
+	if len(c.out.Servers) == 0 {
+		return
+	}
+
+	if len(c.out.Servers) > 1 {
+		c.warn(docs.NodePath{"servers"}, "Swagger 2.0 has single host and base path, only the first server is used")
+	}
+
+	serverUrl := c.out.Servers[0].Url
+
+	if strings.ContainsAny(serverUrl, "{}") {
+		c.warn(docs.NodePath{"servers", "0"}, "server variables are not supported by Swagger 2.0, server %v is not used", serverUrl)
+		return
+	}
+
+	parsed, err := url.Parse(serverUrl)
+	if err != nil {
+		c.warn(docs.NodePath{"servers", "0"}, "invalid server url %v: %v", serverUrl, err)
+		return
+	}
+
+	out.Host = parsed.Host
+	out.BasePath = parsed.Path
+	if parsed.Scheme != "" {
+		out.Schemes = []string{parsed.Scheme}
+	}
+}
+
+// swagger2SecurityDefinitions converts security schemes, names of schemes
+// which can't be expressed are returned
+func (c *CompileContext) swagger2SecurityDefinitions(out *Swagger2Document) []string {
+	var dropped []string
+
+	for _, name := range slices.Sorted(maps.Keys(c.out.Components.SecuritySchemes)) {
+		scheme := c.out.Components.SecuritySchemes[name]
+		at := docs.NodePath{"securitySchemes", name}
+
+		outScheme := Swagger2SecurityScheme{
+			Type:        scheme.Type,
+			Description: scheme.Description,
+		}
+
+		switch {
+		case scheme.Type == "apiKey" && scheme.In != "cookie":
+			outScheme.Name = scheme.Name
+			outScheme.In = scheme.In
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+			outScheme.Type = "basic"
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"):
+			c.warn(at, "bearer security scheme %v is expressed as Authorization header api key in Swagger 2.0", name)
+			outScheme.Type = "apiKey"
+			outScheme.Name = "Authorization"
+			outScheme.In = "header"
+		case scheme.Type == "oauth2" && len(scheme.Flows) != 0:
+			flows := slices.Sorted(maps.Keys(scheme.Flows))
+			if len(flows) > 1 {
+				c.warn(at, "Swagger 2.0 oauth2 security scheme has single flow, only %v flow of %v is used", flows[0], name)
+			}
+
+			flow, _ := scheme.Flows[flows[0]].(map[string]any)
+			outScheme.Flow = swagger2Flows[flows[0]]
+			outScheme.AuthorizationUrl, _ = flow["authorizationUrl"].(string)
+			outScheme.TokenUrl, _ = flow["tokenUrl"].(string)
+			outScheme.Scopes = make(map[string]string)
+			if scopes, ok := flow["scopes"].(map[string]any); ok {
+				for scope, description := range scopes {
+					outScheme.Scopes[scope], _ = description.(string)
+				}
+			}
+		default:
+			c.warn(at, "security scheme %v of type %v can't be expressed in Swagger 2.0, it is dropped", name, scheme.Type)
+			dropped = append(dropped, name)
+			continue
+		}
+
+		if out.SecurityDefinitions == nil {
+			out.SecurityDefinitions = make(map[string]Swagger2SecurityScheme)
+		}
+		out.SecurityDefinitions[name] = outScheme
+	}
+
+	return dropped
+}
+
+func (c *CompileContext) swagger2Operation(op Operation, droppedSchemes []string) Swagger2Operation {
+	out := Swagger2Operation{
+		Summary:     op.Summary,
+		OperationId: op.OperationId,
+		Tags:        op.Tags,
+		Responses:   make(Swagger2Responses, len(op.Responses)),
+		Security:    withoutSchemes(op.Security, droppedSchemes),
+		Deprecated:  op.Deprecated,
+		Extensions:  op.Extensions,
+	}
+
+	for _, param := range op.Parameters {
+		items, ok := c.swagger2Items(param.Schema)
+		if !ok {
+			c.warn(op.at, "schema of %v parameter %v can't be expressed in Swagger 2.0, string is used", param.In, param.Name)
+			items = Swagger2Items{Type: SchemaString}
+		}
+
+		out.Parameters = append(out.Parameters, Swagger2Parameter{
+			Name:          param.Name,
+			In:            string(param.In),
+			Required:      param.Required,
+			Swagger2Items: items,
+			Extensions:    param.Extensions,
+		})
+	}
+
+	if op.RequestBody != nil {
+		c.swagger2Body(op, &out)
+	}
+
+	var produces []string
+
+	for _, code := range slices.Sorted(maps.Keys(op.Responses)) {
+		response := op.Responses[code]
+
+		if strings.Contains(code, "X") {
+			c.warn(op.at, "response code ranges are not supported by Swagger 2.0, response %v is dropped", code)
+			continue
+		}
+
+		outResponse := Swagger2Response{
+			Description: response.Description,
+			Schema:      c.swagger2Content(op, response.Content, "response "+code),
+			Extensions:  response.Extensions,
+			pos:         response.pos,
+		}
+
+		for mediaType := range response.Content {
+			if !slices.Contains(produces, mediaType) {
+				produces = append(produces, mediaType)
+			}
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(response.Headers)) {
+			header := response.Headers[name]
+
+			items, ok := c.swagger2Items(header.Schema)
+			if !ok {
+				c.warn(op.at, "schema of header %v of response %v can't be expressed in Swagger 2.0, string is used", name, code)
+				items = Swagger2Items{Type: SchemaString}
+			}
+
+			if outResponse.Headers == nil {
+				outResponse.Headers = make(map[string]Swagger2Header)
+			}
+			outResponse.Headers[name] = Swagger2Header{
+				Description:   header.Description,
+				Swagger2Items: items,
+			}
+		}
+
+		if len(response.Links) != 0 {
+			c.warn(op.at, "links are not supported by Swagger 2.0, links of response %v are dropped", code)
+		}
+
+		out.Responses[code] = outResponse
+	}
+
+	slices.Sort(produces)
+	out.Produces = produces
+
+	if len(op.Callbacks) != 0 {
+		c.warn(op.at, "callbacks are not supported by Swagger 2.0, they are dropped")
+	}
+
+	return out
+}
+
+// swagger2Body converts request body to body or formData parameters
+func (c *CompileContext) swagger2Body(op Operation, out *Swagger2Operation) {
+	out.Consumes = slices.Sorted(maps.Keys(op.RequestBody.Content))
+
+	form := make(map[string]TypedSchema)
+	other := make(map[string]TypedSchema)
+
+	for mediaType, typed := range op.RequestBody.Content {
+		if slices.Contains(formMediaTypes, mediaType) {
+			form[mediaType] = typed
+		} else {
+			other[mediaType] = typed
+		}
+	}
+
+	if len(form) == 0 {
+		out.Parameters = append(out.Parameters, Swagger2Parameter{
+			Name:     "body",
+			In:       "body",
+			Required: op.RequestBody.Required,
+			Schema:   c.swagger2Content(op, other, "body"),
+		})
+		return
+	}
+
+	if len(other) != 0 {
+		c.warn(op.at, "Swagger 2.0 body can't be both form data and %v, only form data is used", strings.Join(slices.Sorted(maps.Keys(other)), ", "))
+	}
+
+	schema, ok := c.resolveSchema(c.pickContent(op, form, "form data"))
+	if !ok || schema.Type != SchemaObject {
+		c.warn(op.at, "form data of Swagger 2.0 must be an object schema, body is dropped")
+		return
+	}
+
+	for _, property := range schema.Properties {
+		param := Swagger2Parameter{
+			Name:     property.Name,
+			In:       "formData",
+			Required: slices.Contains(schema.Required, property.Name),
+		}
+
+		if propertySchema, ok := c.resolveSchema(property.Schema); ok && propertySchema.Format == "binary" {
+			param.Type = "file"
+		} else if items, ok := c.swagger2Items(property.Schema); ok {
+			param.Swagger2Items = items
+		} else {
+			c.warn(op.at, "form field %v can't be expressed as Swagger 2.0 formData parameter, string is used", property.Name)
+			param.Type = SchemaString
+		}
+
+		out.Parameters = append(out.Parameters, param)
+	}
+}
+
+// swagger2Content picks single schema of content converted to Swagger 2.0
+func (c *CompileContext) swagger2Content(op Operation, content map[string]TypedSchema, what string) *SchemaOrRef {
+	if len(content) == 0 {
+		return nil
+	}
+
+	mapped := mapSchemaOrRef(c.pickContent(op, content, what), swagger2Schema)
+	return &mapped
+}
+
+// pickContent picks single schema of non empty content, JSON is preferred,
+// it warns when media types have different schemas
+func (c *CompileContext) pickContent(op Operation, content map[string]TypedSchema, what string) SchemaOrRef {
+	mediaTypes := slices.Sorted(maps.Keys(content))
+
+	chosen := mediaTypes[0]
+	if _, has := content["application/json"]; has {
+		chosen = "application/json"
+	}
+
+	schema := content[chosen].Schema
+	chosenJSON, _ := json.Marshal(schema)
+
+	for _, mediaType := range mediaTypes {
+		otherJSON, _ := json.Marshal(content[mediaType].Schema)
+		if !bytes.Equal(chosenJSON, otherJSON) {
+			c.warn(op.at, "Swagger 2.0 has single schema of %v, schema of %v is used for all media types", what, chosen)
+			break
+		}
+	}
+
+	return schema
+}
+
+// resolveSchema returns schema, references are resolved from components
+func (c *CompileContext) resolveSchema(s SchemaOrRef) (Schema, bool) {
+	if ref, ok := s.GetRef(); ok {
+		schema, has := c.out.Components.Schemas[strings.TrimPrefix(ref, componentsSchemaPrefix)]
+		return schema, has
+	}
+	return s.GetSchema()
+}
+
+// swagger2Items converts primitive schema or array of primitives
+func (c *CompileContext) swagger2Items(s SchemaOrRef) (Swagger2Items, bool) {
+	schema, ok := c.resolveSchema(s)
+	if !ok || schema.Type == SchemaObject || schema.Type == "" {
+		return Swagger2Items{}, false
+	}
+
+	out := Swagger2Items{
+		Type:        schema.Type,
+		Format:      schema.Format,
+		Default:     schema.Default,
+		Minimum:     schema.Minimum,
+		Maximum:     schema.Maximum,
+		MinLength:   schema.MinLength,
+		MaxLength:   schema.MaxLength,
+		MinItems:    schema.MinItems,
+		MaxItems:    schema.MaxItems,
+		UniqueItems: schema.UniqueItems,
+	}
+
+	if schema.Type == SchemaArray {
+		if schema.Items == nil {
+			return Swagger2Items{}, false
+		}
+
+		items, ok := c.swagger2Items(*schema.Items)
+		if !ok {
+			return Swagger2Items{}, false
+		}
+		out.Items = &items
+	}
+
+	return out, true
+}
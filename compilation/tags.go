@@ -1,8 +1,14 @@
 package compilation
 
 type Tag struct {
-	Name        string `json:"name" yaml:"name"`
-	Description string `json:"description" yaml:"description"`
+	Name        string     `json:"name" yaml:"name"`
+	Description string     `json:"description" yaml:"description"`
+	Extensions  Extensions `json:"-" yaml:",inline"`
+}
+
+func (t Tag) MarshalJSON() ([]byte, error) {
+	type tag Tag
+	return marshalExtendedJSON(tag(t), t.Extensions)
 }
 
 type Tags []Tag
@@ -0,0 +1,597 @@
+package compilation
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+type traitArg struct {
+	name       string
+	def        string
+	hasDefault bool
+	// declared type, nil when untyped
+	typ *Schema
+}
+
+// check validates value against declared type and its range
+func (a traitArg) check(value string) error {
+	if a.typ == nil {
+		return nil
+	}
+
+	if value == "null" && a.typ.nullable {
+		return nil
+	}
+
+	var number float64
+
+	switch a.typ.Type {
+	case SchemaInteger:
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected integer, got %q", value)
+		}
+		number = float64(val)
+	case SchemaNumber:
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected number, got %q", value)
+		}
+		number = val
+	case SchemaBoolean:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("expected boolean, got %q", value)
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	if a.typ.Minimum != nil && number < float64(*a.typ.Minimum) {
+		return fmt.Errorf("value %v is lower than minimum %v", value, *a.typ.Minimum)
+	}
+
+	if a.typ.Maximum != nil && number > float64(*a.typ.Maximum) {
+		return fmt.Errorf("value %v is greater than maximum %v", value, *a.typ.Maximum)
+	}
+
+	return nil
+}
+
+type PrecompiledTrait struct {
+	name   string
+	args   []traitArg
+	target docs.Trait
+	// node of definition, e.g. traits.paged(Max)
+	at docs.NodePath
+}
+
+// evaluatedTrait is trait with arguments substituted, at is node of its
+// definition
+type evaluatedTrait struct {
+	docs.Trait
+	at docs.NodePath
+}
+
+// argsReplacer substitutes #Arg tokens with values, only whole identifiers
+// are replaced, so #Def is left untouched inside #Default.
+type argsReplacer struct {
+	values map[string]string
+	err    error
+}
+
+func isIdentRune(ch byte) bool {
+	return ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+func (r *argsReplacer) Replace(s string) string {
+	var out strings.Builder
+
+	for idx := 0; idx < len(s); idx++ {
+		if s[idx] != '#' {
+			out.WriteByte(s[idx])
+			continue
+		}
+
+		end := idx + 1
+		for end < len(s) && isIdentRune(s[end]) {
+			end++
+		}
+
+		// not an argument token, e.g. #/components
+		if end == idx+1 {
+			out.WriteByte(s[idx])
+			continue
+		}
+
+		name := s[idx+1 : end]
+		value, has := r.values[name]
+
+		if !has {
+			if r.err == nil {
+				r.err = fmt.Errorf("unknown argument #%v in %q", name, s)
+			}
+			out.WriteString(s[idx:end])
+		} else {
+			out.WriteString(value)
+		}
+
+		idx = end - 1
+	}
+
+	return out.String()
+}
+
+func (p *PrecompiledTrait) compileSchema(schema docs.Schema, r *argsReplacer) docs.Schema {
+	switch t := schema.Value.(type) {
+	case string:
+		return docs.Schema{
+			Value: r.Replace(t),
+		}
+	case docs.Properties:
+		// copy, target is shared between evaluations
+		props := make(docs.Properties, len(t))
+
+		for idx, prop := range t {
+			props[idx] = docs.Property{
+				Name:   prop.Name,
+				Schema: p.compileSchema(prop.Schema, r),
+			}
+		}
+
+		return docs.Schema{
+			Value:      props,
+			Extensions: schema.Extensions,
+		}
+	default:
+		panic(fmt.Errorf("invalid schema underlying type: %v", reflect.TypeOf(schema).Name()))
+	}
+}
+
+func (p *PrecompiledTrait) compileParams(params docs.Params, r *argsReplacer) docs.Params {
+	if params == nil {
+		return nil
+	}
+
+	out := make(docs.Params, len(params))
+
+	for idx, param := range params {
+		out[idx] = param
+		out[idx].Schema = p.compileSchema(param.Schema, r)
+	}
+
+	return out
+}
+
+func (p *PrecompiledTrait) compileTypedSchema(typed docs.TypedSchema, r *argsReplacer) docs.TypedSchema {
+	if typed == nil {
+		return nil
+	}
+
+	out := make(docs.TypedSchema, len(typed))
+
+	for mediaType, schema := range typed {
+		out[mediaType] = p.compileSchema(schema, r)
+	}
+
+	return out
+}
+
+func (p *PrecompiledTrait) compileResponses(responses docs.Responses, r *argsReplacer) docs.Responses {
+	if responses == nil {
+		return nil
+	}
+
+	out := make(docs.Responses, len(responses))
+
+	for statusCode, response := range responses {
+		response.TypedSchema = p.compileTypedSchema(response.TypedSchema, r)
+		response.Headers = p.compileParams(response.Headers, r)
+		out[statusCode] = response
+	}
+
+	return out
+}
+
+// bind resolves call values to trait's arguments, positional values first,
+// then named ones, missing values are taken from defaults.
+func (p PrecompiledTrait) bind(call traitCall) (map[string]string, error) {
+	if len(call.positional) > len(p.args) {
+		return nil, fmt.Errorf("Invalid number of values: %v (expected at most: %v)", len(call.positional), len(p.args))
+	}
+
+	values := make(map[string]string, len(p.args))
+
+	for idx, value := range call.positional {
+		values[p.args[idx].name] = value
+	}
+
+	for name, value := range call.named {
+		if !slices.ContainsFunc(p.args, func(arg traitArg) bool { return arg.name == name }) {
+			return nil, fmt.Errorf("unknown argument %v", name)
+		}
+
+		if _, has := values[name]; has {
+			return nil, fmt.Errorf("argument %v given more than once", name)
+		}
+
+		values[name] = value
+	}
+
+	for _, arg := range p.args {
+		if _, has := values[arg.name]; !has {
+			if !arg.hasDefault {
+				return nil, fmt.Errorf("missing value for argument %v", arg.name)
+			}
+
+			values[arg.name] = arg.def
+		}
+
+		if err := arg.check(values[arg.name]); err != nil {
+			return nil, fmt.Errorf("argument %v: %w", arg.name, err)
+		}
+	}
+
+	return values, nil
+}
+
+func (p PrecompiledTrait) Compile(values map[string]string) (docs.Trait, error) {
+	replacer := &argsReplacer{
+		values: values,
+	}
+
+	out := p.target
+
+	out.Params = p.compileParams(p.target.Params, replacer)
+	out.Headers = p.compileParams(p.target.Headers, replacer)
+	out.ResponseHeaders = p.compileParams(p.target.ResponseHeaders, replacer)
+	out.Body = p.compileTypedSchema(p.target.Body, replacer)
+	out.Responses = p.compileResponses(p.target.Responses, replacer)
+
+	if p.target.Traits != nil {
+		out.Traits = make([]string, len(p.target.Traits))
+		for idx, call := range p.target.Traits {
+			out.Traits[idx] = replacer.Replace(call)
+		}
+	}
+
+	if replacer.err != nil {
+		return docs.Trait{}, replacer.err
+	}
+
+	return out, nil
+}
+
+var traitEvExpr = regexp.MustCompile(`^([A-Za-z_]\w*)(?:\(\s*([^()]*?)\s*\))?$`)
+
+// definition arguments can carry types with parenthesised constraints
+var traitDefExpr = regexp.MustCompile(`^([A-Za-z_]\w*)(?:\(\s*(.*?)\s*\))?$`)
+
+var namedValueExpr = regexp.MustCompile(`^([A-Za-z_]\w*)\s*=\s*(.*)$`)
+
+// Name[: type][= default]
+var argDefExpr = regexp.MustCompile(`^([A-Za-z_]\w*)\s*(?::\s*([^=]*?))?\s*(?:=\s*(.*))?$`)
+
+// splitArgs splits comma separated list, skipping commas inside parentheses,
+// empty list gives no elements
+func splitArgs(args string) []string {
+	if strings.TrimSpace(args) == "" {
+		return nil
+	}
+
+	var out []string
+	depth, start := 0, 0
+
+	for idx, ch := range args {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(args[start:idx]))
+				start = idx + 1
+			}
+		}
+	}
+
+	return append(out, strings.TrimSpace(args[start:]))
+}
+
+// parseArgType parses primitive schema expression used as argument type
+func parseArgType(expr string) (*Schema, error) {
+	schema, err := parseSchema(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	out, ok := schema.GetSchema()
+	if !ok || out.Type == SchemaArray {
+		return nil, fmt.Errorf("argument type must be a primitive, got %v", expr)
+	}
+
+	return &out, nil
+}
+
+func (c *CompileContext) compileTrait(ident string, args string, t docs.Trait, at docs.NodePath) (PrecompiledTrait, error) {
+	out := PrecompiledTrait{
+		name:   ident,
+		target: t,
+		at:     at,
+	}
+
+	for _, arg := range splitArgs(args) {
+		groups := argDefExpr.FindStringSubmatch(arg)
+		if groups == nil {
+			return PrecompiledTrait{}, fmt.Errorf("invalid argument %v of trait %v", arg, ident)
+		}
+
+		outArg := traitArg{
+			name:       groups[1],
+			def:        groups[3],
+			hasDefault: strings.Contains(arg, "="),
+		}
+
+		if groups[2] != "" {
+			typ, err := parseArgType(groups[2])
+			if err != nil {
+				return PrecompiledTrait{}, fmt.Errorf("argument %v of trait %v: %w", outArg.name, ident, err)
+			}
+			outArg.typ = typ
+		}
+
+		if outArg.hasDefault {
+			if err := outArg.check(outArg.def); err != nil {
+				return PrecompiledTrait{}, fmt.Errorf("default of argument %v of trait %v: %w", outArg.name, ident, err)
+			}
+		}
+
+		if slices.ContainsFunc(out.args, func(a traitArg) bool { return a.name == outArg.name }) {
+			return PrecompiledTrait{}, fmt.Errorf("duplicated argument %v of trait %v", outArg.name, ident)
+		}
+
+		out.args = append(out.args, outArg)
+	}
+
+	return out, nil
+}
+
+// compileTraits reports invalid definitions, their uses are not reported
+// again
+func (c *CompileContext) compileTraits() {
+	c.compiledTraits = make(map[string]PrecompiledTrait, len(c.in.Traits))
+
+	for expr, trait := range c.in.Traits {
+		exprGrp := traitDefExpr.FindStringSubmatch(expr)
+		if exprGrp == nil {
+			c.report(docs.Errorf(docs.NodePath{"traits", expr}, "invalid trait definition expression: %v", expr))
+			continue
+		}
+		ident := exprGrp[1]
+		args := exprGrp[2]
+
+		if _, has := c.compiledTraits[ident]; has {
+			c.report(docs.Errorf(docs.NodePath{"traits", expr}, "trait %v defined more than once", ident))
+			continue
+		}
+
+		result, err := c.compileTrait(ident, args, trait, docs.NodePath{"traits", expr})
+
+		if err != nil {
+			c.report(docs.At(err, "traits", expr))
+			c.failedTraits[ident] = true
+			continue
+		}
+
+		c.compiledTraits[ident] = result
+	}
+
+	c.checkTraitCycles()
+}
+
+// checkTraitCycles reports every cycle of trait includes once, at definition
+// of its first trait. Traits of cycles are marked as failed, so methods
+// applying them don't report the cycle again.
+func (c *CompileContext) checkTraitCycles() {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	var (
+		state  = make(map[string]int, len(c.compiledTraits))
+		stack  []string
+		cyclic []string
+	)
+
+	var visit func(ident string)
+	visit = func(ident string) {
+		state[ident] = visiting
+		stack = append(stack, ident)
+
+		for _, expr := range c.compiledTraits[ident].target.Traits {
+			// includes with invalid expressions are reported when evaluated
+			call, err := parseTraitCall(expr)
+			if err != nil {
+				continue
+			}
+
+			if _, has := c.compiledTraits[call.ident]; !has {
+				continue
+			}
+
+			switch state[call.ident] {
+			case visiting:
+				cycle := stack[slices.Index(stack, call.ident):]
+				c.report(docs.Errorf(c.compiledTraits[call.ident].at, "trait cycle detected: %v -> %v", strings.Join(cycle, " -> "), call.ident))
+				cyclic = append(cyclic, cycle...)
+			case 0:
+				visit(call.ident)
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[ident] = visited
+	}
+
+	for _, ident := range slices.Sorted(maps.Keys(c.compiledTraits)) {
+		if state[ident] == 0 {
+			visit(ident)
+		}
+	}
+
+	for _, ident := range cyclic {
+		delete(c.compiledTraits, ident)
+		c.failedTraits[ident] = true
+	}
+}
+
+type traitCall struct {
+	ident      string
+	positional []string
+	named      map[string]string
+}
+
+func parseTraitCall(expr string) (traitCall, error) {
+	groups := traitEvExpr.FindStringSubmatch(strings.TrimSpace(expr))
+
+	if groups == nil {
+		return traitCall{}, fmt.Errorf("invalid trait evaluate expression: %v\n expected: ident[(arg(,args)...)]", expr)
+	}
+
+	call := traitCall{
+		ident: groups[1],
+	}
+
+	for _, value := range splitArgs(groups[2]) {
+		if named := namedValueExpr.FindStringSubmatch(value); named != nil {
+			if call.named == nil {
+				call.named = make(map[string]string)
+			}
+			call.named[named[1]] = named[2]
+			continue
+		}
+
+		if call.named != nil {
+			return traitCall{}, fmt.Errorf("positional value %v after named values in %v", value, expr)
+		}
+
+		call.positional = append(call.positional, value)
+	}
+
+	return call, nil
+}
+
+// evaluateTrait returns traits included by evaluated one followed by itself,
+// stack holds idents of traits being evaluated, to detect cycles through
+// includes substituted from arguments, others are reported by
+// checkTraitCycles.
+func (c *CompileContext) evaluateTrait(expr string, stack []string) ([]evaluatedTrait, error) {
+	call, err := parseTraitCall(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	trait, has := c.compiledTraits[call.ident]
+
+	if !has {
+		if c.failedTraits[call.ident] {
+			return nil, errReported
+		}
+		return nil, fmt.Errorf("no %v trait found", call.ident)
+	}
+
+	c.usedTraits[call.ident] = true
+
+	if slices.Contains(stack, call.ident) {
+		return nil, fmt.Errorf("trait cycle detected: %v -> %v", strings.Join(stack, " -> "), call.ident)
+	}
+
+	stack = append(stack, call.ident)
+
+	values, err := trait.bind(call)
+	if err != nil {
+		return nil, fmt.Errorf("trait %v: %w", call.ident, err)
+	}
+
+	compiled, err := trait.Compile(values)
+	if err != nil {
+		return nil, fmt.Errorf("trait %v: %w", call.ident, err)
+	}
+
+	var out []evaluatedTrait
+
+	for _, included := range compiled.Traits {
+		res, err := c.evaluateTrait(included, stack)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res...)
+	}
+
+	return append(out, evaluatedTrait{compiled, trait.at}), nil
+}
+
+// methodTraits returns trait invocations inherited from path nodes, without
+// the ones excluded by method, followed by method's own.
+func (c *CompileContext) methodTraits(method *docs.Method, inherited []string) ([]string, error) {
+	for _, ident := range method.ExcludeTraits {
+		if _, has := c.compiledTraits[ident]; !has && !c.failedTraits[ident] {
+			return nil, fmt.Errorf("no %v trait found to exclude", ident)
+		}
+	}
+
+	out := make([]string, 0, len(inherited)+len(method.Traits))
+
+	for _, expr := range inherited {
+		call, err := parseTraitCall(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(method.ExcludeTraits, call.ident) {
+			out = append(out, expr)
+		}
+	}
+
+	return append(out, method.Traits...), nil
+}
+
+// evaluateTraits flattens method's trait invocations in order, included
+// traits come before the including one, so it takes precedence over them.
+func (c *CompileContext) evaluateTraits(traits []string) ([]evaluatedTrait, error) {
+	if traits == nil {
+		return nil, nil
+	}
+
+	var out []evaluatedTrait
+	var reported error
+
+	for _, in := range traits {
+		res, err := c.evaluateTrait(in, nil)
+		if errors.Is(err, errReported) {
+			// keep looking for errors not reported yet
+			reported = err
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res...)
+	}
+
+	if reported != nil {
+		return nil, reported
+	}
+
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package compilation
+
+// mapOperations returns path with every operation rewritten by fn
+func (p Path) mapOperations(fn func(Operation) Operation) Path {
+	for _, op := range []**Operation{&p.Get, &p.Post, &p.Put, &p.Patch, &p.Delete} {
+		if *op != nil {
+			mapped := fn(**op)
+			*op = &mapped
+		}
+	}
+	return p
+}
+
+func (p Paths) mapOperations(fn func(Operation) Operation) Paths {
+	if p == nil {
+		return nil
+	}
+
+	out := make(Paths, len(p))
+	for key, item := range p {
+		out[key] = item.mapOperations(fn)
+	}
+	return out
+}
+
+// schemaMapper rewrites schema or reference, it's applied to nested
+// schemas before their parents
+type schemaMapper func(SchemaOrRef) SchemaOrRef
+
+// schemasOnly adapts fn to mapper leaving references unchanged
+func schemasOnly(fn func(Schema) Schema) schemaMapper {
+	return func(s SchemaOrRef) SchemaOrRef {
+		if schema, ok := s.value.(Schema); ok {
+			s.value = fn(schema)
+		}
+		return s
+	}
+}
+
+// mapSchemaOrRef rewrites s and its nested schemas with fn, shared slices
+// are not modified
+func mapSchemaOrRef(s SchemaOrRef, fn schemaMapper) SchemaOrRef {
+	schema, ok := s.value.(Schema)
+	if !ok {
+		return fn(s)
+	}
+
+	if schema.Properties != nil {
+		properties := make(Properties, len(schema.Properties))
+		for i, property := range schema.Properties {
+			properties[i] = Property{
+				Name:   property.Name,
+				Schema: mapSchemaOrRef(property.Schema, fn),
+			}
+		}
+		schema.Properties = properties
+	}
+
+	if schema.Items != nil {
+		items := mapSchemaOrRef(*schema.Items, fn)
+		schema.Items = &items
+	}
+
+	if schema.Extensions != nil {
+		schema.Extensions = mapRawRefs(map[string]any(schema.Extensions), fn).(map[string]any)
+	}
+
+	s.value = schema
+	return fn(s)
+}
+
+// mapRawRefs rewrites references in keywords kept verbatim, e.g. oneOf of
+// bundled external schemas, references fn maps to schemas are kept
+func mapRawRefs(v any, fn schemaMapper) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			out[key] = mapRawRefs(value, fn)
+		}
+
+		if ref, ok := v["$ref"].(string); ok {
+			if mapped, ok := fn(NewSchemaRef(ref)).GetRef(); ok {
+				out["$ref"] = mapped
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = mapRawRefs(value, fn)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func mapContent(content map[string]TypedSchema, fn schemaMapper) map[string]TypedSchema {
+	if content == nil {
+		return nil
+	}
+
+	out := make(map[string]TypedSchema, len(content))
+	for mediaType, typed := range content {
+		out[mediaType] = TypedSchema{
+			Schema: mapSchemaOrRef(typed.Schema, fn),
+		}
+	}
+	return out
+}
+
+func mapHeaders(headers map[string]Header, fn schemaMapper) map[string]Header {
+	if headers == nil {
+		return nil
+	}
+
+	out := make(map[string]Header, len(headers))
+	for name, header := range headers {
+		header.Schema = mapSchemaOrRef(header.Schema, fn)
+		out[name] = header
+	}
+	return out
+}
+
+func (o Operation) mapSchemas(fn schemaMapper) Operation {
+	if o.Parameters != nil {
+		params := make([]Parameter, len(o.Parameters))
+		for i, param := range o.Parameters {
+			param.Schema = mapSchemaOrRef(param.Schema, fn)
+			params[i] = param
+		}
+		o.Parameters = params
+	}
+
+	if o.RequestBody != nil {
+		body := *o.RequestBody
+		body.Content = mapContent(body.Content, fn)
+		o.RequestBody = &body
+	}
+
+	if o.Responses != nil {
+		responses := make(Responses, len(o.Responses))
+		for code, response := range o.Responses {
+			response.Content = mapContent(response.Content, fn)
+			response.Headers = mapHeaders(response.Headers, fn)
+			responses[code] = response
+		}
+		o.Responses = responses
+	}
+
+	if o.Callbacks != nil {
+		callbacks := make(map[string]Callback, len(o.Callbacks))
+		for name, callback := range o.Callbacks {
+			callbacks[name] = callback.mapOperations(func(op Operation) Operation {
+				return op.mapSchemas(fn)
+			})
+		}
+		o.Callbacks = callbacks
+	}
+
+	return o
+}
+
+// mapSchemas rewrites every schema of document, including components,
+// parameters, bodies, responses, callbacks and webhooks
+func (d *Document) mapSchemas(fn schemaMapper) {
+	if d.Components.Schemas != nil {
+		schemas := make(Schemas, len(d.Components.Schemas))
+		for name, schema := range d.Components.Schemas {
+			if mapped, ok := mapSchemaOrRef(NewSchemaDef(schema), fn).GetSchema(); ok {
+				schemas[name] = mapped
+			}
+		}
+		d.Components.Schemas = schemas
+	}
+
+	mapOperation := func(op Operation) Operation {
+		return op.mapSchemas(fn)
+	}
+
+	d.Paths = d.Paths.mapOperations(mapOperation)
+	d.Webhooks = d.Webhooks.mapOperations(mapOperation)
+}
@@ -0,0 +1,314 @@
+package decompilation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// references followed before giving up, guards against cycles
+const maxRefDepth = 32
+
+type decompiler struct {
+	root        object
+	diagnostics docs.Diagnostics
+	// qapi names of component schemas, aliases are mapped to their targets
+	schemaNames map[string]string
+	usedNames   map[string]bool
+	schemas     object
+	// object definitions hoisted from places only expressions are allowed
+	hoisted object
+	// trait of global security requirements, applied to top level paths
+	securityTrait string
+	operations    []*operation
+	traits        object
+	// trait names of tags applied to single operations
+	tagTraits        map[string]string
+	defaultResponses object
+}
+
+// Decompile converts OpenAPI 3.0 or 3.1 document to qapi document. Parts qapi
+// can't express are dropped and reported as diagnostics located by paths of
+// data.
+func Decompile(data []byte) ([]byte, docs.Diagnostics, error) {
+	var root object
+	if err := yaml.UnmarshalWithOptions(data, &root, yaml.UseOrderedMap()); err != nil {
+		return nil, nil, fmt.Errorf("unable to parse document: %w", err)
+	}
+
+	if has(root, "swagger") {
+		return nil, nil, errors.New("swagger 2.0 documents are not supported, OpenAPI 3.0 or 3.1 expected")
+	}
+
+	if version := fmt.Sprint(get(root, "openapi")); !strings.HasPrefix(version, "3.") {
+		return nil, nil, fmt.Errorf("unsupported OpenAPI version %v, 3.0 or 3.1 expected", version)
+	}
+
+	d := &decompiler{
+		root:        root,
+		schemaNames: make(map[string]string),
+		usedNames:   make(map[string]bool),
+		tagTraits:   make(map[string]string),
+	}
+
+	out, err := yaml.MarshalWithOptions(d.document(), yaml.IndentSequence(true))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, d.diagnostics, nil
+}
+
+func (d *decompiler) warn(at docs.NodePath, format string, args ...any) {
+	d.diagnostics = append(d.diagnostics, docs.Diagnostic{
+		Severity: docs.SeverityWarning,
+		Path:     slices.Clone(at),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *decompiler) info(at docs.NodePath, format string, args ...any) {
+	d.diagnostics = append(d.diagnostics, docs.Diagnostic{
+		Severity: docs.SeverityInfo,
+		Path:     slices.Clone(at),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// dropped reports keys of o other than handled ones, extensions are
+// handled when keepExtensions is set
+func (d *decompiler) dropped(at docs.NodePath, o object, keepExtensions bool, handled ...string) {
+	var out []string
+
+	for _, key := range keys(o) {
+		if slices.Contains(handled, key) || keepExtensions && strings.HasPrefix(key, "x-") {
+			continue
+		}
+		out = append(out, key)
+	}
+
+	if len(out) != 0 {
+		d.warn(at, "%v can't be expressed in qapi, dropped", strings.Join(out, ", "))
+	}
+}
+
+// resolve follows local references of v, returned path locates the object
+// which was found. Nil is returned when v isn't an object or can't be
+// resolved.
+func (d *decompiler) resolve(v any, at docs.NodePath) (object, docs.NodePath) {
+	o, ok := v.(object)
+	if !ok {
+		d.warn(at, "object expected, dropped")
+		return nil, at
+	}
+
+	for range maxRefDepth {
+		ref := getString(o, "$ref")
+		if ref == "" {
+			return o, at
+		}
+
+		if !strings.HasPrefix(ref, "#/") {
+			d.warn(at, "external reference %v can't be expressed, dropped", ref)
+			return nil, at
+		}
+
+		path := docs.NodePath{}
+		for _, segment := range strings.Split(ref[2:], "/") {
+			path = append(path, unescapePointer(segment))
+		}
+
+		target, ok := lookup(d.root, path).(object)
+		if !ok {
+			d.warn(at, "reference %v not found, dropped", ref)
+			return nil, at
+		}
+
+		o, at = target, path
+	}
+
+	d.warn(at, "references nested too deep, dropped")
+	return nil, at
+}
+
+// lookup returns node of document at path
+func lookup(node any, path docs.NodePath) any {
+	for _, segment := range path {
+		switch n := node.(type) {
+		case object:
+			node = get(n, segment)
+		case []any:
+			idx, err := strconv.Atoi(segment)
+			if err != nil || idx < 0 || idx >= len(n) {
+				return nil
+			}
+			node = n[idx]
+		default:
+			return nil
+		}
+	}
+	return node
+}
+
+// document converts the whole document, keys are ordered as qapi documents
+// usually are
+func (d *decompiler) document() object {
+	d.dropped(nil, d.root, false, "openapi", "info", "servers", "tags", "paths", "webhooks", "components", "security")
+
+	components := getObject(d.root, "components")
+	d.dropped(docs.NodePath{"components"}, components, false,
+		"schemas", "securitySchemes", "parameters", "responses", "requestBodies", "headers", "links", "callbacks", "pathItems", "examples")
+
+	out := object{
+		{Key: "info", Value: d.infoObject()},
+		{Key: "servers", Value: d.servers()},
+	}
+
+	if tags := d.tags(); len(tags) != 0 {
+		out = append(out, yaml.MapItem{Key: "tags", Value: tags})
+	}
+
+	if schemes := d.securitySchemes(); len(schemes) != 0 {
+		out = append(out, yaml.MapItem{Key: "securitySchemes", Value: schemes})
+	}
+
+	d.componentSchemas()
+
+	tree := d.paths()
+	webhooks := d.webhooks()
+
+	d.factorDefaultResponses()
+	d.factorTraits()
+
+	// security declared by operations replaces security of traits
+	if security := getList(d.root, "security"); len(security) != 0 {
+		d.securityTrait = d.traitName("secured")
+		d.traits = append(d.traits, yaml.MapItem{Key: d.securityTrait, Value: object{{Key: "security", Value: security}}})
+	}
+
+	// traits of tags are registered while paths are written
+	paths := object{}
+	for _, node := range tree.children {
+		value := d.nodeValue(node, nil)
+		if d.securityTrait != "" {
+			value = insertTraits(value, d.securityTrait)
+		}
+		paths = append(paths, yaml.MapItem{Key: node.segment, Value: value})
+	}
+
+	webhooksOut := object{}
+	for _, webhook := range webhooks {
+		webhooksOut = append(webhooksOut, yaml.MapItem{Key: webhook.key, Value: d.itemValue(webhook.item)})
+	}
+
+	schemas := append(d.schemas, d.hoisted...)
+	if len(schemas) != 0 {
+		out = append(out, yaml.MapItem{Key: "schemas", Value: schemas})
+	}
+
+	if len(d.traits) != 0 {
+		out = append(out, yaml.MapItem{Key: "traits", Value: d.traits})
+	}
+
+	if len(d.defaultResponses) != 0 {
+		out = append(out, yaml.MapItem{Key: "defaultResponses", Value: d.defaultResponses})
+	}
+
+	if len(paths) != 0 {
+		out = append(out, yaml.MapItem{Key: "paths", Value: paths})
+	}
+
+	if len(webhooksOut) != 0 {
+		out = append(out, yaml.MapItem{Key: "webhooks", Value: webhooksOut})
+	}
+
+	return out
+}
+
+func (d *decompiler) infoObject() object {
+	at := docs.NodePath{"info"}
+	info := getObject(d.root, "info")
+
+	out := object{
+		{Key: "title", Value: getString(info, "title")},
+		{Key: "version", Value: fmt.Sprint(get(info, "version"))},
+	}
+
+	if description := getString(info, "description"); description != "" {
+		out = append(out, yaml.MapItem{Key: "description", Value: description})
+	}
+
+	d.dropped(at, info, true, "title", "version", "description")
+
+	return append(out, extensions(info)...)
+}
+
+func (d *decompiler) servers() []any {
+	out := []any{}
+
+	for i, raw := range getList(d.root, "servers") {
+		at := docs.NodePath{"servers", strconv.Itoa(i)}
+		server, _ := raw.(object)
+
+		value := object{{Key: "url", Value: getString(server, "url")}}
+		if description := getString(server, "description"); description != "" {
+			value = append(value, yaml.MapItem{Key: "description", Value: description})
+		}
+
+		d.dropped(at, server, true, "url", "description")
+		out = append(out, append(value, extensions(server)...))
+	}
+
+	return out
+}
+
+func (d *decompiler) tags() []any {
+	var out []any
+
+	for i, raw := range getList(d.root, "tags") {
+		at := docs.NodePath{"tags", strconv.Itoa(i)}
+		tag, _ := raw.(object)
+
+		value := object{{Key: "name", Value: getString(tag, "name")}}
+		if description := getString(tag, "description"); description != "" {
+			value = append(value, yaml.MapItem{Key: "description", Value: description})
+		}
+
+		d.dropped(at, tag, true, "name", "description")
+		out = append(out, append(value, extensions(tag)...))
+	}
+
+	return out
+}
+
+// fields of security schemes qapi declares, the same as OpenAPI ones
+var securitySchemeFields = []string{"type", "description", "name", "in", "scheme", "bearerFormat", "flows", "openIdConnectUrl"}
+
+func (d *decompiler) securitySchemes() object {
+	schemes := getObject(getObject(d.root, "components"), "securitySchemes")
+	out := object{}
+
+	for _, name := range keys(schemes) {
+		scheme, at := d.resolve(get(schemes, name), docs.NodePath{"components", "securitySchemes", name})
+		if scheme == nil {
+			continue
+		}
+
+		value := object{}
+		for _, item := range scheme {
+			if slices.Contains(securitySchemeFields, fmt.Sprint(item.Key)) {
+				value = append(value, item)
+			}
+		}
+
+		d.dropped(at, scheme, false, securitySchemeFields...)
+		out = append(out, yaml.MapItem{Key: name, Value: value})
+	}
+
+	return out
+}
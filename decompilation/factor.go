@@ -0,0 +1,195 @@
+package decompilation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// factorDefaultResponses moves error responses most operations return in
+// the same form to defaultResponses, operations without such response
+// exclude it
+func (d *decompiler) factorDefaultResponses() {
+	var codes []string
+	for _, op := range d.operations {
+		for _, code := range keys(op.responses) {
+			if (code[0] == '4' || code[0] == '5') && !slices.Contains(codes, code) {
+				codes = append(codes, code)
+			}
+		}
+	}
+	slices.Sort(codes)
+
+	for _, code := range codes {
+		counts := make(map[string]int)
+		var best string
+		var response any
+
+		for _, op := range d.operations {
+			if !has(op.responses, code) {
+				continue
+			}
+
+			key := canonical(get(op.responses, code))
+			counts[key]++
+
+			if counts[key] > counts[best] {
+				best, response = key, get(op.responses, code)
+			}
+		}
+
+		if counts[best] < 2 || counts[best]*2 <= len(d.operations) {
+			continue
+		}
+
+		d.defaultResponses = append(d.defaultResponses, yaml.MapItem{Key: code, Value: response})
+
+		for _, op := range d.operations {
+			switch {
+			case !has(op.responses, code):
+				op.exclude = append(op.exclude, code)
+			case canonical(get(op.responses, code)) == best:
+				op.responses = without(op.responses, code)
+			}
+		}
+	}
+}
+
+// factorTraits moves groups of parameters shared by the same operations to
+// traits, path parameters are left as they are named by paths
+func (d *decompiler) factorTraits() {
+	type group struct {
+		params     []param
+		operations []*operation
+	}
+
+	var keys []string
+	operations := make(map[string][]*operation)
+	params := make(map[string]param)
+
+	for _, op := range d.operations {
+		for _, p := range op.params {
+			if p.in == "path" {
+				continue
+			}
+
+			key := p.key()
+			if _, has := params[key]; !has {
+				keys = append(keys, key)
+				params[key] = p
+			}
+			operations[key] = append(operations[key], op)
+		}
+	}
+
+	// parameters are grouped by operations using them
+	var groups []*group
+	byOperations := make(map[string]*group)
+
+	for _, key := range keys {
+		if len(operations[key]) < 2 {
+			continue
+		}
+
+		var ids []string
+		for _, op := range operations[key] {
+			ids = append(ids, fmt.Sprintf("%p", op))
+		}
+		id := strings.Join(ids, ",")
+
+		g, has := byOperations[id]
+		if !has {
+			g = &group{operations: operations[key]}
+			byOperations[id] = g
+			groups = append(groups, g)
+		}
+		g.params = append(g.params, params[key])
+	}
+
+	for _, g := range groups {
+		if len(g.params) < 2 {
+			continue
+		}
+
+		var names []string
+		trait := object{}
+		var query, headers []any
+
+		for _, p := range g.params {
+			names = append(names, p.name())
+			if p.in == "header" {
+				headers = append(headers, p.value)
+			} else {
+				query = append(query, p.value)
+			}
+		}
+
+		if len(query) != 0 {
+			trait = append(trait, yaml.MapItem{Key: "params", Value: query})
+		}
+		if len(headers) != 0 {
+			trait = append(trait, yaml.MapItem{Key: "headers", Value: headers})
+		}
+
+		name := d.traitName(traitNameOf(names))
+		d.traits = append(d.traits, yaml.MapItem{Key: name, Value: trait})
+
+		for _, op := range g.operations {
+			op.params = slices.DeleteFunc(op.params, func(p param) bool {
+				return slices.ContainsFunc(g.params, func(other param) bool {
+					return other.key() == p.key()
+				})
+			})
+			op.traits = append(op.traits, name)
+		}
+
+		d.info(g.operations[0].at, "parameters %v of %d operations moved to trait %v",
+			strings.Join(names, ", "), len(g.operations), name)
+	}
+}
+
+// traitNameOf names trait of parameters, e.g. pageLimit or pageParams
+func traitNameOf(names []string) string {
+	if len(names) > 3 {
+		return camelCase(names[0]) + "Params"
+	}
+
+	var sb strings.Builder
+	for i, name := range names {
+		if i == 0 {
+			sb.WriteString(camelCase(name))
+		} else {
+			sb.WriteString(pascalCase(name))
+		}
+	}
+	return sb.String()
+}
+
+// traitName returns name no other trait uses, based on name
+func (d *decompiler) traitName(name string) string {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "t" + name
+	}
+
+	out := name
+	for i := 2; has(d.traits, out); i++ {
+		out = fmt.Sprintf("%v%d", name, i)
+	}
+	return out
+}
+
+// tagTrait returns name of trait adding tag, used for tags single
+// operations of path item have
+func (d *decompiler) tagTrait(tag string) string {
+	if name, has := d.tagTraits[tag]; has {
+		return name
+	}
+
+	name := d.traitName("tag" + pascalCase(tag))
+	d.traits = append(d.traits, yaml.MapItem{Key: name, Value: object{{Key: "tags", Value: []any{tag}}}})
+	d.tagTraits[tag] = name
+
+	return name
+}
@@ -0,0 +1,493 @@
+package decompilation
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// methods qapi path items declare, in order they are written
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+var (
+	statusCodeRegex = regexp.MustCompile(`^[1-5](\d\d|XX)$`)
+	mediaTypeRegex  = regexp.MustCompile(`\w+/\w+`)
+	// literal path segment, as qapi paths declare them
+	segmentRegex   = regexp.MustCompile(`^[a-zA-Z0-9$-_.+!*'()]+$`)
+	paramNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+)
+
+// param is converted parameter, in is query, path or header
+type param struct {
+	in    string
+	value object
+}
+
+func (p param) name() string {
+	return getString(p.value, "name")
+}
+
+// key identifies equal parameters
+func (p param) key() string {
+	return p.in + "\n" + canonical(p.value)
+}
+
+type operation struct {
+	method      string
+	at          docs.NodePath
+	id          string
+	description string
+	deprecated  bool
+	tags        []string
+	traits      []string
+	params      []param
+	body        object
+	responses   object
+	// default responses the operation doesn't return
+	exclude     []string
+	security    any
+	hasSecurity bool
+	callbacks   []namedCallback
+	extensions  object
+}
+
+type pathItem struct {
+	operations []*operation
+	extensions object
+}
+
+type namedItem struct {
+	key  string
+	item *pathItem
+}
+
+type namedCallback struct {
+	name  string
+	items []namedItem
+}
+
+// paths converts paths to tree of path segments
+func (d *decompiler) paths() *pathNode {
+	paths := getObject(d.root, "paths")
+	root := &pathNode{}
+
+	for _, path := range keys(paths) {
+		at := docs.NodePath{"paths", path}
+
+		segments, rename, ok := d.segments(path, at)
+		if !ok {
+			continue
+		}
+
+		item := d.pathItem(get(paths, path), at, path, rename)
+		if item == nil || len(item.operations) == 0 {
+			continue
+		}
+
+		node := root
+		for _, segment := range segments {
+			node = node.child(segment)
+		}
+
+		if node.item != nil {
+			d.warn(at, "path %v is already defined, dropped", strings.Join(segments, ""))
+			continue
+		}
+		node.item = item
+	}
+
+	root.collapse()
+	return root
+}
+
+// segments splits path to segments of qapi path keys, path parameters of
+// names qapi can't express are renamed
+func (d *decompiler) segments(path string, at docs.NodePath) ([]string, map[string]string, bool) {
+	trimmed := strings.TrimSuffix(path, "/")
+
+	if trimmed == "" {
+		d.warn(at, "root path / can't be expressed, dropped")
+		return nil, nil, false
+	}
+
+	if trimmed != path {
+		d.warn(at, "trailing slash of %v can't be expressed, dropped", path)
+	}
+
+	if !strings.HasPrefix(trimmed, "/") {
+		d.warn(at, "path %v doesn't start with /, dropped", path)
+		return nil, nil, false
+	}
+
+	var out []string
+	rename := make(map[string]string)
+
+	for _, segment := range strings.Split(trimmed[1:], "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok && strings.HasSuffix(name, "}") {
+			name = strings.TrimSuffix(name, "}")
+
+			if !paramNameRegex.MatchString(name) {
+				renamed := camelCase(name)
+				if !paramNameRegex.MatchString(renamed) {
+					d.warn(at, "path parameter %v can't be expressed, path dropped", name)
+					return nil, nil, false
+				}
+
+				d.warn(at, "path parameter %v renamed to %v, qapi names are made of letters and digits", name, renamed)
+				rename[name] = renamed
+				name = renamed
+			}
+
+			out = append(out, "/{"+name+"}")
+			continue
+		}
+
+		if !segmentRegex.MatchString(segment) {
+			d.warn(at, "path segment %v can't be expressed, path dropped", segment)
+			return nil, nil, false
+		}
+
+		out = append(out, "/"+segment)
+	}
+
+	return out, rename, true
+}
+
+// webhooks converts webhooks of OpenAPI 3.1
+func (d *decompiler) webhooks() []namedItem {
+	webhooks := getObject(d.root, "webhooks")
+
+	var out []namedItem
+
+	for _, name := range keys(webhooks) {
+		item := d.pathItem(get(webhooks, name), docs.NodePath{"webhooks", name}, name, nil)
+		if item != nil && len(item.operations) != 0 {
+			out = append(out, namedItem{key: name, item: item})
+		}
+	}
+
+	return out
+}
+
+// pathItem converts path item of path, its parameters are merged into each
+// operation
+func (d *decompiler) pathItem(raw any, at docs.NodePath, path string, rename map[string]string) *pathItem {
+	o, at := d.resolve(raw, at)
+	if o == nil {
+		return nil
+	}
+
+	hint := pascalCase(path)
+	common := d.params(getList(o, "parameters"), append(slices.Clone(at), "parameters"), rename, hint)
+
+	item := &pathItem{extensions: extensions(o)}
+
+	for _, method := range keys(o) {
+		if !slices.Contains(methods, method) {
+			continue
+		}
+
+		methodAt := append(slices.Clone(at), method)
+		raw, ok := get(o, method).(object)
+		if !ok {
+			d.warn(methodAt, "operation must be an object, dropped")
+			continue
+		}
+
+		item.operations = append(item.operations, d.operation(raw, methodAt, method, path, common, rename))
+	}
+
+	d.dropped(at, o, true, append(slices.Clone(methods), "parameters")...)
+
+	return item
+}
+
+func (d *decompiler) operation(o object, at docs.NodePath, method, path string, common []param, rename map[string]string) *operation {
+	op := &operation{
+		method:     method,
+		at:         at,
+		id:         getString(o, "operationId"),
+		extensions: extensions(o),
+	}
+	d.operations = append(d.operations, op)
+
+	hint := cmp.Or(pascalCase(op.id), pascalCase(method+" "+path))
+
+	summary, description := getString(o, "summary"), getString(o, "description")
+	op.description = cmp.Or(summary, description)
+	if summary != "" && description != "" {
+		d.warn(append(slices.Clone(at), "description"), "description next to summary can't be expressed, dropped")
+	}
+
+	op.deprecated = getBool(o, "deprecated")
+
+	for _, tag := range getList(o, "tags") {
+		op.tags = append(op.tags, fmt.Sprint(tag))
+	}
+
+	op.params = slices.Clone(common)
+	for _, p := range d.params(getList(o, "parameters"), append(slices.Clone(at), "parameters"), rename, hint) {
+		idx := slices.IndexFunc(op.params, func(other param) bool {
+			return other.in == p.in && other.name() == p.name()
+		})
+
+		if idx == -1 {
+			op.params = append(op.params, p)
+		} else {
+			op.params[idx] = p
+		}
+	}
+
+	if has(o, "requestBody") {
+		op.body = d.requestBody(get(o, "requestBody"), append(slices.Clone(at), "requestBody"), hint+"Body")
+	}
+
+	op.responses = d.responses(getObject(o, "responses"), append(slices.Clone(at), "responses"), hint)
+
+	if has(o, "security") {
+		op.security, op.hasSecurity = get(o, "security"), true
+	}
+
+	op.callbacks = d.callbacks(getObject(o, "callbacks"), append(slices.Clone(at), "callbacks"))
+
+	d.dropped(at, o, true, "operationId", "summary", "description", "deprecated", "tags", "parameters", "requestBody", "responses", "security", "callbacks")
+
+	return op
+}
+
+// params converts parameters, path parameters are renamed by rename
+func (d *decompiler) params(list []any, at docs.NodePath, rename map[string]string, hint string) []param {
+	var out []param
+
+	for i, raw := range list {
+		o, paramAt := d.resolve(raw, append(slices.Clone(at), strconv.Itoa(i)))
+		if o == nil {
+			continue
+		}
+
+		name, in := getString(o, "name"), getString(o, "in")
+
+		switch in {
+		case "query", "header":
+		case "path":
+			if renamed, has := rename[name]; has {
+				name = renamed
+			}
+		default:
+			d.warn(paramAt, "%v parameter %v can't be expressed, dropped", in, name)
+			continue
+		}
+
+		value := object{{Key: "name", Value: name}}
+
+		if has(o, "schema") {
+			value = append(value, yaml.MapItem{
+				Key:   "schema",
+				Value: d.schema(get(o, "schema"), append(slices.Clone(paramAt), "schema"), hint+pascalCase(name)),
+			})
+		} else {
+			d.warn(paramAt, "parameter %v without schema can't be expressed, string used", name)
+			value = append(value, yaml.MapItem{Key: "schema", Value: "string"})
+		}
+
+		if getBool(o, "required") {
+			value = append(value, yaml.MapItem{Key: "required", Value: true})
+		}
+
+		d.dropped(paramAt, o, true, "name", "in", "schema", "required")
+
+		out = append(out, param{in: in, value: append(value, extensions(o)...)})
+	}
+
+	return out
+}
+
+func (d *decompiler) requestBody(raw any, at docs.NodePath, hint string) object {
+	o, at := d.resolve(raw, at)
+	if o == nil {
+		return nil
+	}
+
+	handled := []string{"content"}
+	if getBool(o, "required") {
+		handled = append(handled, "required")
+	}
+
+	out := d.content(getObject(o, "content"), append(slices.Clone(at), "content"), hint)
+	d.dropped(at, o, false, handled...)
+
+	return out
+}
+
+// content converts schemas of media types
+func (d *decompiler) content(o object, at docs.NodePath, hint string) object {
+	out := object{}
+
+	for _, mediaType := range keys(o) {
+		mediaAt := append(slices.Clone(at), mediaType)
+
+		if !mediaTypeRegex.MatchString(mediaType) {
+			d.warn(mediaAt, "media type %v can't be expressed, dropped", mediaType)
+			continue
+		}
+
+		media := getObject(o, mediaType)
+		if !has(media, "schema") {
+			d.warn(mediaAt, "media type %v without schema can't be expressed, dropped", mediaType)
+			continue
+		}
+
+		out = append(out, yaml.MapItem{
+			Key:   mediaType,
+			Value: d.schema(get(media, "schema"), append(slices.Clone(mediaAt), "schema"), hint),
+		})
+
+		d.dropped(mediaAt, media, false, "schema")
+	}
+
+	return out
+}
+
+func (d *decompiler) responses(o object, at docs.NodePath, hint string) object {
+	out := object{}
+
+	for _, code := range keys(o) {
+		codeAt := append(slices.Clone(at), code)
+
+		if !statusCodeRegex.MatchString(code) {
+			d.warn(codeAt, "response %v can't be expressed, dropped", code)
+			continue
+		}
+
+		if response := d.response(get(o, code), codeAt, hint+"Response"); response != nil {
+			out = append(out, yaml.MapItem{Key: code, Value: response})
+		}
+	}
+
+	return out
+}
+
+func (d *decompiler) response(raw any, at docs.NodePath, hint string) object {
+	o, at := d.resolve(raw, at)
+	if o == nil {
+		return nil
+	}
+
+	out := object{{Key: "description", Value: getString(o, "description")}}
+
+	headers := getObject(o, "headers")
+	var headersOut []any
+
+	for _, name := range keys(headers) {
+		header, headerAt := d.resolve(get(headers, name), slices.Concat(at, docs.NodePath{"headers", name}))
+		if header == nil {
+			continue
+		}
+
+		value := object{{Key: "name", Value: name}}
+
+		if has(header, "schema") {
+			value = append(value, yaml.MapItem{
+				Key:   "schema",
+				Value: d.schema(get(header, "schema"), append(slices.Clone(headerAt), "schema"), hint+pascalCase(name)),
+			})
+		} else {
+			d.warn(headerAt, "header %v without schema can't be expressed, string used", name)
+			value = append(value, yaml.MapItem{Key: "schema", Value: "string"})
+		}
+
+		if getBool(header, "required") {
+			value = append(value, yaml.MapItem{Key: "required", Value: true})
+		}
+
+		d.dropped(headerAt, header, true, "schema", "required")
+		headersOut = append(headersOut, append(value, extensions(header)...))
+	}
+
+	if len(headersOut) != 0 {
+		out = append(out, yaml.MapItem{Key: "headers", Value: headersOut})
+	}
+
+	if links := d.links(getObject(o, "links"), append(slices.Clone(at), "links")); len(links) != 0 {
+		out = append(out, yaml.MapItem{Key: "links", Value: links})
+	}
+
+	out = append(out, d.content(getObject(o, "content"), append(slices.Clone(at), "content"), hint)...)
+
+	d.dropped(at, o, true, "description", "headers", "links", "content")
+
+	return append(out, extensions(o)...)
+}
+
+// links converts links, operations are referenced by their ids
+func (d *decompiler) links(o object, at docs.NodePath) object {
+	out := object{}
+
+	for _, name := range keys(o) {
+		link, linkAt := d.resolve(get(o, name), append(slices.Clone(at), name))
+		if link == nil {
+			continue
+		}
+
+		id := getString(link, "operationId")
+		if id == "" {
+			d.warn(linkAt, "link %v without operationId can't be expressed, dropped", name)
+			continue
+		}
+
+		value := object{{Key: "operation", Value: id}}
+
+		if params := get(link, "parameters"); params != nil {
+			value = append(value, yaml.MapItem{Key: "params", Value: params})
+		}
+		if body := get(link, "requestBody"); body != nil {
+			value = append(value, yaml.MapItem{Key: "body", Value: body})
+		}
+		if description := getString(link, "description"); description != "" {
+			value = append(value, yaml.MapItem{Key: "description", Value: description})
+		}
+
+		d.dropped(linkAt, link, false, "operationId", "parameters", "requestBody", "description")
+		out = append(out, yaml.MapItem{Key: name, Value: value})
+	}
+
+	return out
+}
+
+func (d *decompiler) callbacks(o object, at docs.NodePath) []namedCallback {
+	var out []namedCallback
+
+	for _, name := range keys(o) {
+		callback, callbackAt := d.resolve(get(o, name), append(slices.Clone(at), name))
+		if callback == nil {
+			continue
+		}
+
+		c := namedCallback{name: name}
+
+		for _, expression := range keys(callback) {
+			itemAt := append(slices.Clone(callbackAt), expression)
+
+			if strings.HasPrefix(expression, "x-") {
+				d.warn(itemAt, "callback extension %v can't be expressed, dropped", expression)
+				continue
+			}
+
+			if item := d.pathItem(get(callback, expression), itemAt, name, nil); item != nil && len(item.operations) != 0 {
+				c.items = append(c.items, namedItem{key: expression, item: item})
+			}
+		}
+
+		if len(c.items) != 0 {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,143 @@
+package decompilation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+)
+
+// object is decoded YAML mapping, keys keep source order
+type object = yaml.MapSlice
+
+// get returns value of key, nil when missing
+func get(o object, key string) any {
+	for _, item := range o {
+		if fmt.Sprint(item.Key) == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func has(o object, key string) bool {
+	for _, item := range o {
+		if fmt.Sprint(item.Key) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func getObject(o object, key string) object {
+	out, _ := get(o, key).(object)
+	return out
+}
+
+func getString(o object, key string) string {
+	out, _ := get(o, key).(string)
+	return out
+}
+
+func getBool(o object, key string) bool {
+	out, _ := get(o, key).(bool)
+	return out
+}
+
+func getList(o object, key string) []any {
+	out, _ := get(o, key).([]any)
+	return out
+}
+
+// keys returns keys of o as strings, in source order
+func keys(o object) []string {
+	out := make([]string, len(o))
+	for i, item := range o {
+		out[i] = fmt.Sprint(item.Key)
+	}
+	return out
+}
+
+// set replaces value of key or appends it
+func set(o object, key string, value any) object {
+	for i, item := range o {
+		if fmt.Sprint(item.Key) == key {
+			o[i].Value = value
+			return o
+		}
+	}
+	return append(o, yaml.MapItem{Key: key, Value: value})
+}
+
+// extensions returns x-* keys of o
+func extensions(o object) object {
+	var out object
+	for _, item := range o {
+		if key := fmt.Sprint(item.Key); strings.HasPrefix(key, "x-") {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// integer returns value of decoded number, when it's whole
+func integer(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, false
+		}
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// canonical returns serialized value, used to compare decoded values
+func canonical(v any) string {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(out)
+}
+
+// pascalCase joins words of s, e.g. order_line to OrderLine
+func pascalCase(s string) string {
+	var sb strings.Builder
+	upper := true
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// camelCase joins words of s, e.g. user_id to userId
+func camelCase(s string) string {
+	out := []rune(pascalCase(s))
+	if len(out) != 0 {
+		out[0] = unicode.ToLower(out[0])
+	}
+	return string(out)
+}
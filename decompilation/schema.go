@@ -0,0 +1,422 @@
+package decompilation
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+const componentsSchemaPrefix = "#/components/schemas/"
+
+var (
+	primitiveTypes = []string{"string", "integer", "number", "boolean"}
+	// property names of object definitions
+	propertyNameRegex = regexp.MustCompile(`^\w+$`)
+	// references to external files schema expressions can hold
+	externalRefRegex = regexp.MustCompile(`^[\w.\-/]+#[\w.\-/$~]+$`)
+	nonWordRegex     = regexp.MustCompile(`\W+`)
+)
+
+// componentSchemas names and converts components.schemas, schemas which
+// only reference other schema are replaced by it
+func (d *decompiler) componentSchemas() {
+	schemas := getObject(getObject(d.root, "components"), "schemas")
+	aliases := make(map[string]string)
+	replaced := make(map[string]bool)
+
+	for _, name := range keys(schemas) {
+		qname := nonWordRegex.ReplaceAllString(name, "_")
+		if qname != name {
+			d.warn(docs.NodePath{"components", "schemas", name}, "schema %v renamed to %v, qapi names are made of letters, digits and _", name, qname)
+		}
+		d.schemaNames[name] = d.uniqueName(qname)
+
+		if s, ok := get(schemas, name).(object); ok && len(s) == 1 {
+			if target, ok := strings.CutPrefix(getString(s, "$ref"), componentsSchemaPrefix); ok {
+				aliases[name] = unescapePointer(target)
+			}
+		}
+	}
+
+	for name := range aliases {
+		target, seen := name, map[string]bool{}
+		for aliases[target] != "" && !seen[target] {
+			seen[target] = true
+			target = aliases[target]
+		}
+		if !seen[target] {
+			d.warn(docs.NodePath{"components", "schemas", name}, "schema %v only references %v, references to it are replaced", name, target)
+			d.schemaNames[name] = d.schemaNames[target]
+			replaced[name] = true
+		}
+	}
+
+	for _, name := range keys(schemas) {
+		at := docs.NodePath{"components", "schemas", name}
+
+		if replaced[name] {
+			continue
+		}
+
+		qname := d.schemaNames[name]
+		d.schemas = append(d.schemas, yaml.MapItem{Key: qname, Value: d.schema(get(schemas, name), at, qname)})
+	}
+
+	d.schemas = append(d.schemas, d.hoisted...)
+	d.hoisted = nil
+}
+
+// uniqueName returns name not used by other schema
+func (d *decompiler) uniqueName(name string) string {
+	out := name
+	for i := 2; d.usedNames[out]; i++ {
+		out = fmt.Sprintf("%v%d", name, i)
+	}
+	d.usedNames[out] = true
+	return out
+}
+
+// schema converts JSON Schema to schema expression or object definition,
+// hint names object definitions which have to be hoisted
+func (d *decompiler) schema(raw any, at docs.NodePath, hint string) any {
+	s, ok := raw.(object)
+	if !ok {
+		if raw != true {
+			d.warn(at, "invalid schema, string used")
+			return "string"
+		}
+		s = object{}
+	}
+
+	s, nullable := d.unwrap(s, at)
+
+	if ref := getString(s, "$ref"); ref != "" {
+		d.dropped(at, s, false, "$ref")
+		if nullable {
+			d.warn(at, "nullable reference can't be expressed, nullable dropped")
+		}
+		return d.ref(ref, at, hint)
+	}
+
+	typ := getString(s, "type")
+
+	switch {
+	case slices.Contains(primitiveTypes, typ):
+		return d.primitive(s, typ, nullable, at)
+	case typ == "array":
+		return d.array(s, nullable, at, hint)
+	case typ == "object" || has(s, "properties"):
+		if nullable {
+			d.warn(at, "nullable object definition can't be expressed, nullable dropped")
+		}
+		return d.object(s, at, hint)
+	case typ == "":
+		d.warn(at, "schema without type can't be expressed, empty object used")
+		d.dropped(at, s, true)
+		return object{}
+	default:
+		d.warn(at, "unknown type %v, string used", typ)
+		return "string"
+	}
+}
+
+// expression converts schema used where only schema expression is allowed,
+// object definitions are hoisted to named schemas
+func (d *decompiler) expression(raw any, at docs.NodePath, hint string) string {
+	switch v := d.schema(raw, at, hint).(type) {
+	case string:
+		return v
+	default:
+		name := d.uniqueName(hint)
+		d.hoisted = append(d.hoisted, yaml.MapItem{Key: name, Value: v})
+		return "<" + name + ">"
+	}
+}
+
+// unwrap removes nullability and single element compositions, returned
+// schema is a copy without keywords handled
+func (d *decompiler) unwrap(s object, at docs.NodePath) (object, bool) {
+	nullable := false
+
+	for {
+		s = slices.Clone(s)
+
+		if types, ok := get(s, "type").([]any); ok {
+			var other []string
+			for _, t := range types {
+				if t == "null" {
+					nullable = true
+				} else {
+					other = append(other, fmt.Sprint(t))
+				}
+			}
+
+			switch len(other) {
+			case 0:
+				d.warn(at, "null type can't be expressed, nullable string used")
+				s = set(s, "type", "string")
+			case 1:
+				s = set(s, "type", other[0])
+			default:
+				d.warn(at, "multiple types %v can't be expressed, %v used", strings.Join(other, ", "), other[0])
+				s = set(s, "type", other[0])
+			}
+		}
+
+		if has(s, "nullable") {
+			nullable = nullable || getBool(s, "nullable")
+			s = without(s, "nullable")
+		}
+
+		var composed string
+		for _, keyword := range []string{"allOf", "oneOf", "anyOf"} {
+			if has(s, keyword) {
+				composed = keyword
+			}
+		}
+
+		if composed == "" {
+			return s, nullable
+		}
+
+		alternatives := getList(s, composed)
+		rest := without(s, composed)
+
+		var other []any
+		for _, alternative := range alternatives {
+			if o, ok := alternative.(object); ok && len(o) == 1 && get(o, "type") == "null" {
+				nullable = true
+				continue
+			}
+			other = append(other, alternative)
+		}
+
+		if len(other) != 1 {
+			d.warn(append(slices.Clone(at), composed), "%v of %d schemas can't be expressed, first one used", composed, len(other))
+		}
+
+		next, ok := any(nil), false
+		if len(other) != 0 {
+			next = other[0]
+			_, ok = next.(object)
+		}
+
+		if !ok {
+			d.warn(at, "%v without schemas, string used", composed)
+			return object{{Key: "type", Value: "string"}}, nullable
+		}
+
+		// keywords next to composition, e.g. description
+		d.dropped(at, rest, false)
+		s = next.(object)
+	}
+}
+
+// without returns copy of s without key
+func without(s object, key string) object {
+	return slices.DeleteFunc(slices.Clone(s), func(item yaml.MapItem) bool {
+		return fmt.Sprint(item.Key) == key
+	})
+}
+
+func unescapePointer(segment string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+}
+
+// ref converts reference to schema expression, local references other than
+// component schemas are inlined
+func (d *decompiler) ref(ref string, at docs.NodePath, hint string) any {
+	if name, ok := strings.CutPrefix(ref, componentsSchemaPrefix); ok {
+		qname, has := d.schemaNames[unescapePointer(name)]
+		if !has {
+			d.warn(at, "referenced schema %v not found, string used", name)
+			return "string"
+		}
+		return "<" + qname + ">"
+	}
+
+	if strings.HasPrefix(ref, "#") {
+		target, targetAt := d.resolve(object{{Key: "$ref", Value: ref}}, at)
+		if target == nil {
+			return "string"
+		}
+		return d.schema(target, targetAt, hint)
+	}
+
+	if !externalRefRegex.MatchString(ref) {
+		d.warn(at, "external reference %v can't be expressed, string used", ref)
+		return "string"
+	}
+
+	return "<" + ref + ">"
+}
+
+// primitive converts schema of primitive type to expression
+func (d *decompiler) primitive(s object, typ string, nullable bool, at docs.NodePath) string {
+	var params []string
+
+	if format := getString(s, "format"); format != "" {
+		if strings.ContainsAny(format, ",()") {
+			d.warn(at, "format %v can't be expressed, dropped", format)
+		} else {
+			params = append(params, "$"+format)
+		}
+	}
+
+	if has(s, "minimum") || has(s, "maximum") {
+		var bounds [2]string
+		valid := true
+
+		for i, keyword := range []string{"minimum", "maximum"} {
+			if !has(s, keyword) {
+				continue
+			}
+			if n, ok := integer(get(s, keyword)); ok {
+				bounds[i] = strconv.FormatInt(n, 10)
+			} else {
+				valid = false
+			}
+		}
+
+		if valid {
+			params = append(params, bounds[0]+":"+bounds[1])
+		} else {
+			d.warn(at, "fractional range can't be expressed, dropped")
+		}
+	}
+
+	if has(s, "default") {
+		if value, ok := defaultValue(get(s, "default"), typ); ok {
+			params = append(params, value)
+		} else {
+			d.warn(at, "default %v can't be expressed, dropped", get(s, "default"))
+		}
+	}
+
+	d.dropped(at, s, false, "type", "format", "minimum", "maximum", "default")
+
+	out := typ
+	if nullable {
+		out += "?"
+	}
+	if len(params) != 0 {
+		out += "(" + strings.Join(params, ",") + ")"
+	}
+	return out
+}
+
+// defaultValue formats default value of primitive schema expression
+func defaultValue(v any, typ string) (string, bool) {
+	if v == nil {
+		return "null", true
+	}
+
+	switch typ {
+	case "string":
+		s, ok := v.(string)
+		if !ok || strings.ContainsAny(s, `",()`) {
+			return "", false
+		}
+		return `"` + s + `"`, true
+	case "integer":
+		n, ok := integer(v)
+		return strconv.FormatInt(n, 10), ok
+	case "number":
+		if n, ok := integer(v); ok {
+			return strconv.FormatInt(n, 10), true
+		}
+		f, ok := v.(float64)
+		return strconv.FormatFloat(f, 'f', -1, 64), ok
+	case "boolean":
+		b, ok := v.(bool)
+		return strconv.FormatBool(b), ok
+	default:
+		return "", false
+	}
+}
+
+// array converts array schema to expression
+func (d *decompiler) array(s object, nullable bool, at docs.NodePath, hint string) string {
+	item := "string"
+
+	if items, ok := get(s, "items").(object); ok {
+		item = d.expression(items, append(slices.Clone(at), "items"), hint+"Item")
+	} else {
+		d.warn(at, "array without items schema, string items used")
+	}
+
+	var params []string
+
+	if getBool(s, "uniqueItems") {
+		params = append(params, "*")
+	}
+
+	minItems, hasMin := integer(get(s, "minItems"))
+	maxItems, hasMax := integer(get(s, "maxItems"))
+
+	switch {
+	case hasMin && hasMax:
+		params = append(params, fmt.Sprintf("%d:%d", minItems, maxItems))
+	case hasMax:
+		params = append(params, strconv.FormatInt(maxItems, 10))
+	case hasMin:
+		d.warn(at, "minItems without maxItems can't be expressed, dropped")
+	}
+
+	d.dropped(at, s, false, "type", "items", "uniqueItems", "minItems", "maxItems")
+
+	// ? after bare primitive makes items nullable, empty parameters end it
+	if nullable && slices.Contains(primitiveTypes, item) {
+		item += "()"
+	}
+
+	out := item
+	if nullable {
+		out += "?"
+	}
+	return out + "[" + strings.Join(params, ",") + "]"
+}
+
+// object converts object schema to object definition
+func (d *decompiler) object(s object, at docs.NodePath, hint string) object {
+	out := object{}
+
+	required := make(map[string]bool)
+	for _, name := range getList(s, "required") {
+		required[fmt.Sprint(name)] = true
+	}
+
+	properties := getObject(s, "properties")
+
+	for _, name := range keys(properties) {
+		propertyAt := slices.Concat(at, docs.NodePath{"properties", name})
+
+		if !propertyNameRegex.MatchString(name) {
+			d.warn(propertyAt, "property name %v can't be expressed, dropped", name)
+			continue
+		}
+
+		key := name
+		if !required[name] {
+			key += "?"
+		}
+
+		out = append(out, yaml.MapItem{
+			Key:   key,
+			Value: d.schema(get(properties, name), propertyAt, hint+pascalCase(name)),
+		})
+	}
+
+	if additional := get(s, "additionalProperties"); additional != nil && additional != false {
+		d.warn(at, "additionalProperties can't be expressed, dropped")
+	}
+
+	d.dropped(at, s, true, "type", "properties", "required", "additionalProperties")
+
+	return append(out, extensions(s)...)
+}
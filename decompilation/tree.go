@@ -0,0 +1,200 @@
+package decompilation
+
+import (
+	"slices"
+
+	"github.com/goccy/go-yaml"
+)
+
+// pathNode is node of path tree, segment is key of the node in its parent
+type pathNode struct {
+	segment  string
+	item     *pathItem
+	children []*pathNode
+}
+
+func (n *pathNode) child(segment string) *pathNode {
+	for _, child := range n.children {
+		if child.segment == segment {
+			return child
+		}
+	}
+
+	child := &pathNode{segment: segment}
+	n.children = append(n.children, child)
+	return child
+}
+
+// collapse joins chains of nodes without operations, e.g. /api with single
+// nested /v1 to /api/v1
+func (n *pathNode) collapse() {
+	for i, child := range n.children {
+		for child.item == nil && len(child.children) == 1 {
+			only := child.children[0]
+			only.segment = child.segment + only.segment
+			child = only
+		}
+
+		child.collapse()
+		n.children[i] = child
+	}
+}
+
+// operations returns operations of n and nodes nested in it
+func (n *pathNode) operations() []*operation {
+	var out []*operation
+	if n.item != nil {
+		out = append(out, n.item.operations...)
+	}
+	for _, child := range n.children {
+		out = append(out, child.operations()...)
+	}
+	return out
+}
+
+// commonTags returns tags all operations have, in order of the first one
+func commonTags(operations []*operation) []string {
+	if len(operations) == 0 {
+		return nil
+	}
+
+	var out []string
+	for _, tag := range operations[0].tags {
+		if !slices.ContainsFunc(operations[1:], func(op *operation) bool {
+			return !slices.Contains(op.tags, tag)
+		}) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// nodeValue writes path node, tags all its operations have and nodes above
+// it don't are declared on it
+func (d *decompiler) nodeValue(n *pathNode, inherited []string) object {
+	out := object{}
+
+	tags := slices.DeleteFunc(commonTags(n.operations()), func(tag string) bool {
+		return slices.Contains(inherited, tag)
+	})
+
+	if len(tags) != 0 {
+		out = append(out, yaml.MapItem{Key: "tags", Value: tags})
+		inherited = slices.Concat(inherited, tags)
+	}
+
+	if n.item != nil {
+		for _, op := range n.item.operations {
+			out = append(out, yaml.MapItem{Key: op.method, Value: d.operationValue(op, inherited)})
+		}
+		out = append(out, n.item.extensions...)
+	}
+
+	for _, child := range n.children {
+		out = append(out, yaml.MapItem{Key: child.segment, Value: d.nodeValue(child, inherited)})
+	}
+
+	return out
+}
+
+// itemValue writes path item of webhook or callback
+func (d *decompiler) itemValue(item *pathItem) object {
+	out := object{}
+
+	tags := commonTags(item.operations)
+	if len(tags) != 0 {
+		out = append(out, yaml.MapItem{Key: "tags", Value: tags})
+	}
+
+	for _, op := range item.operations {
+		out = append(out, yaml.MapItem{Key: op.method, Value: d.operationValue(op, tags)})
+	}
+
+	return append(out, item.extensions...)
+}
+
+// operationValue writes method, tags not declared by path nodes are added
+// by traits
+func (d *decompiler) operationValue(op *operation, inherited []string) object {
+	out := object{}
+
+	if op.id != "" {
+		out = append(out, yaml.MapItem{Key: "id", Value: op.id})
+	}
+
+	if op.description != "" {
+		out = append(out, yaml.MapItem{Key: "description", Value: op.description})
+	}
+
+	if op.deprecated {
+		out = append(out, yaml.MapItem{Key: "deprecated", Value: true})
+	}
+
+	traits := slices.Clone(op.traits)
+	for _, tag := range op.tags {
+		if !slices.Contains(inherited, tag) {
+			traits = append(traits, d.tagTrait(tag))
+		}
+	}
+
+	if len(traits) != 0 {
+		out = append(out, yaml.MapItem{Key: "traits", Value: traits})
+	}
+
+	if len(op.exclude) != 0 {
+		out = append(out, yaml.MapItem{Key: "defaultResponses", Value: object{{Key: "exclude", Value: op.exclude}}})
+	}
+
+	var params, headers []any
+	for _, p := range op.params {
+		if p.in == "header" {
+			headers = append(headers, p.value)
+		} else {
+			params = append(params, p.value)
+		}
+	}
+
+	if len(params) != 0 {
+		out = append(out, yaml.MapItem{Key: "params", Value: params})
+	}
+
+	if len(headers) != 0 {
+		out = append(out, yaml.MapItem{Key: "headers", Value: headers})
+	}
+
+	if len(op.body) != 0 {
+		out = append(out, yaml.MapItem{Key: "body", Value: op.body})
+	}
+
+	if len(op.responses) != 0 {
+		out = append(out, yaml.MapItem{Key: "responses", Value: op.responses})
+	}
+
+	if op.hasSecurity {
+		out = append(out, yaml.MapItem{Key: "security", Value: op.security})
+	}
+
+	if len(op.callbacks) != 0 {
+		callbacks := object{}
+		for _, callback := range op.callbacks {
+			items := object{}
+			for _, item := range callback.items {
+				items = append(items, yaml.MapItem{Key: item.key, Value: d.itemValue(item.item)})
+			}
+			callbacks = append(callbacks, yaml.MapItem{Key: callback.name, Value: items})
+		}
+		out = append(out, yaml.MapItem{Key: "callbacks", Value: callbacks})
+	}
+
+	return append(out, op.extensions...)
+}
+
+// insertTraits declares traits applied to all methods of path node, after
+// its tags
+func insertTraits(node object, traits ...string) object {
+	idx := 0
+	if has(node, "tags") {
+		idx = 1
+	}
+	return slices.Insert(node, idx, yaml.MapItem{Key: "traits", Value: traits})
+}
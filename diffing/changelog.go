@@ -0,0 +1,118 @@
+package diffing
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// untagged groups changes of operations without tags in changelogs
+const untagged = "Other"
+
+var kindOrder = []Kind{KindAdded, KindChanged, KindDeprecated, KindRemoved}
+
+// WriteChangelog writes Markdown changelog of changes titled title, grouped
+// by tags of operations, then by operations. Operations with several tags
+// are listed under each of them.
+func WriteChangelog(w io.Writer, title string, changes []Change) error {
+	if _, err := fmt.Fprintf(w, "# %v\n\n", title); err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No API changes.")
+		return err
+	}
+
+	if breaking, _ := count(changes); breaking != 0 {
+		if _, err := fmt.Fprintf(w, "**%v breaking changes.**\n\n", breaking); err != nil {
+			return err
+		}
+	}
+
+	byTag := make(map[string][]Change)
+	for _, change := range changes {
+		tags := change.Tags
+		if len(tags) == 0 {
+			tags = []string{untagged}
+		}
+		for _, tag := range tags {
+			byTag[tag] = append(byTag[tag], change)
+		}
+	}
+
+	tags := make([]string, 0, len(byTag))
+	for tag := range byTag {
+		tags = append(tags, tag)
+	}
+	slices.SortFunc(tags, func(a, b string) int {
+		switch {
+		case a == untagged:
+			return 1
+		case b == untagged:
+			return -1
+		}
+		return cmp.Compare(a, b)
+	})
+
+	for _, tag := range tags {
+		if _, err := fmt.Fprintf(w, "## %v\n\n", tag); err != nil {
+			return err
+		}
+
+		// changes are ordered by operation already
+		tagChanges := byTag[tag]
+		for len(tagChanges) != 0 {
+			operation := tagChanges[0].Operation()
+			end := 1
+			for end < len(tagChanges) && tagChanges[end].Operation() == operation {
+				end++
+			}
+
+			if err := writeOperation(w, operation, tagChanges[:end]); err != nil {
+				return err
+			}
+			tagChanges = tagChanges[end:]
+		}
+	}
+
+	return nil
+}
+
+func writeOperation(w io.Writer, operation string, changes []Change) error {
+	if _, err := fmt.Fprintf(w, "### `%v`\n\n", operation); err != nil {
+		return err
+	}
+
+	changes = slices.Clone(changes)
+	slices.SortStableFunc(changes, func(a, b Change) int {
+		return cmp.Compare(slices.Index(kindOrder, a.Kind), slices.Index(kindOrder, b.Kind))
+	})
+
+	for _, change := range changes {
+		// kind is already the label, e.g. "response 400" instead of
+		// "response 400 added", changes of whole operations keep theirs,
+		// e.g. "operation removed"
+		message := change.Message
+		if change.Kind != KindChanged && message != "operation "+string(change.Kind) {
+			message = strings.TrimSuffix(message, " "+string(change.Kind))
+		}
+
+		line := fmt.Sprintf("- **%v**: %v", kindName(change.Kind), message)
+		if change.Breaking {
+			line += " _(breaking)_"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func kindName(k Kind) string {
+	return strings.ToUpper(string(k[:1])) + string(k[1:])
+}
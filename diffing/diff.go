@@ -0,0 +1,474 @@
+package diffing
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/compilation"
+)
+
+// Kind of change, used to group changelogs
+type Kind string
+
+const (
+	KindAdded      Kind = "added"
+	KindChanged    Kind = "changed"
+	KindDeprecated Kind = "deprecated"
+	KindRemoved    Kind = "removed"
+)
+
+// Change is single difference of compiled documents
+type Change struct {
+	Kind     Kind `json:"kind"`
+	Breaking bool `json:"breaking"`
+	// method and path of changed operation, for webhooks path is its name
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Webhook bool   `json:"webhook,omitempty"`
+	// tags of the operation in the new document, or the old one when it
+	// was removed
+	Tags    []string `json:"tags,omitempty"`
+	Message string   `json:"message"`
+}
+
+// Operation returns label of changed operation, e.g. GET /users/{id}
+func (c Change) Operation() string {
+	if c.Webhook {
+		return fmt.Sprintf("%v webhook %v", strings.ToUpper(c.Method), c.Path)
+	}
+	return fmt.Sprintf("%v %v", strings.ToUpper(c.Method), c.Path)
+}
+
+// HasBreaking reports whether any of changes is breaking
+func HasBreaking(changes []Change) bool {
+	return slices.ContainsFunc(changes, func(c Change) bool { return c.Breaking })
+}
+
+// object is JSON object of compiled document
+type object = map[string]any
+
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+// operation of compiled document
+type operation struct {
+	method  string
+	path    string
+	webhook bool
+	// names of path params in order of the path
+	pathParams []string
+	value      object
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]*)\}`)
+
+// operationKey matches operations with renamed path params, e.g.
+// /users/{id} and /users/{userId}
+func operationKey(method, path string, webhook bool) string {
+	if webhook {
+		return "webhook " + method + " " + path
+	}
+	return method + " " + pathParam.ReplaceAllString(path, "{}")
+}
+
+// differ compares documents, changes are reported for the current operation
+type differ struct {
+	old, new object
+	current  operation
+	tags     []string
+	// compared pairs of references by field
+	visited map[string]bool
+	// pairs of references being compared, to stop on recursive schemas
+	comparing map[string]bool
+	changes   []Change
+}
+
+func (d *differ) report(kind Kind, breaking bool, format string, args ...any) {
+	d.changes = append(d.changes, Change{
+		Kind:     kind,
+		Breaking: breaking,
+		Method:   d.current.method,
+		Path:     d.current.path,
+		Webhook:  d.current.webhook,
+		Tags:     d.tags,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// toObject converts compiled document to its JSON form
+func toObject(doc *compilation.Document) (object, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var out object
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func getObject(o object, key string) object {
+	value, _ := o[key].(map[string]any)
+	return value
+}
+
+func getList(o object, key string) []any {
+	value, _ := o[key].([]any)
+	return value
+}
+
+func getString(o object, key string) string {
+	value, _ := o[key].(string)
+	return value
+}
+
+func getBool(o object, key string) bool {
+	value, _ := o[key].(bool)
+	return value
+}
+
+func getStrings(o object, key string) []string {
+	var out []string
+	for _, item := range getList(o, key) {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// operations returns operations of paths and webhooks by operationKey
+func operations(doc object) map[string]operation {
+	out := make(map[string]operation)
+
+	add := func(section string, webhook bool) {
+		for path, item := range getObject(doc, section) {
+			item, _ := item.(map[string]any)
+
+			var params []string
+			for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+				params = append(params, match[1])
+			}
+
+			for _, method := range methods {
+				if op := getObject(item, method); op != nil {
+					out[operationKey(method, path, webhook)] = operation{
+						method:     method,
+						path:       path,
+						webhook:    webhook,
+						pathParams: params,
+						value:      op,
+					}
+				}
+			}
+		}
+	}
+
+	add("paths", false)
+	add("webhooks", true)
+
+	return out
+}
+
+// compareOperations orders operations of paths before webhooks, by path,
+// then by method
+func compareOperations(a, b operation) int {
+	return cmp.Or(
+		cmp.Compare(fmt.Sprint(a.webhook), fmt.Sprint(b.webhook)),
+		cmp.Compare(pathParam.ReplaceAllString(a.path, "{}"), pathParam.ReplaceAllString(b.path, "{}")),
+		cmp.Compare(slices.Index(methods, a.method), slices.Index(methods, b.method)),
+	)
+}
+
+// Compare returns changes from old to new document, ordered by operation.
+// Changes of request are breaking when requests valid before may be
+// rejected, changes of responses when clients may receive what they didn't
+// expect.
+func Compare(old, new *compilation.Document) ([]Change, error) {
+	oldObject, err := toObject(old)
+	if err != nil {
+		return nil, err
+	}
+	newObject, err := toObject(new)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{
+		old: oldObject,
+		new: newObject,
+	}
+
+	oldOperations, newOperations := operations(oldObject), operations(newObject)
+
+	var all []operation
+	for key, op := range newOperations {
+		if _, has := oldOperations[key]; !has {
+			all = append(all, op)
+		}
+	}
+	all = slices.AppendSeq(all, maps.Values(oldOperations))
+	slices.SortFunc(all, compareOperations)
+
+	for _, op := range all {
+		key := operationKey(op.method, op.path, op.webhook)
+		oldOp, inOld := oldOperations[key]
+		newOp, inNew := newOperations[key]
+
+		switch {
+		case !inNew:
+			d.current, d.tags = oldOp, getStrings(oldOp.value, "tags")
+			d.report(KindRemoved, true, "operation removed")
+		case !inOld:
+			d.current, d.tags = newOp, getStrings(newOp.value, "tags")
+			d.report(KindAdded, false, "operation added")
+		default:
+			d.current, d.tags = newOp, getStrings(newOp.value, "tags")
+			// schemas shared by operations are reported for each of them
+			d.visited = make(map[string]bool)
+			d.comparing = make(map[string]bool)
+			d.operation(oldOp, newOp)
+		}
+	}
+
+	return d.changes, nil
+}
+
+// direction of data, request data is sent by clients, response data is
+// received by them. For webhooks it's the other way around.
+type direction int
+
+const (
+	request direction = iota
+	response
+)
+
+func (d *differ) direction(dir direction) direction {
+	if d.current.webhook {
+		return 1 - dir
+	}
+	return dir
+}
+
+func (d *differ) operation(old, new operation) {
+	if oldId, newId := getString(old.value, "operationId"), getString(new.value, "operationId"); oldId != newId {
+		switch {
+		case oldId == "":
+			d.report(KindChanged, false, "operationId %v added", newId)
+		case newId == "":
+			d.report(KindChanged, true, "operationId %v removed", oldId)
+		default:
+			d.report(KindChanged, true, "operationId changed from %v to %v", oldId, newId)
+		}
+	}
+
+	switch oldDeprecated, newDeprecated := getBool(old.value, "deprecated"), getBool(new.value, "deprecated"); {
+	case !oldDeprecated && newDeprecated:
+		d.report(KindDeprecated, false, "operation deprecated")
+	case oldDeprecated && !newDeprecated:
+		d.report(KindChanged, false, "operation no longer deprecated")
+	}
+
+	if oldSummary, newSummary := getString(old.value, "summary"), getString(new.value, "summary"); oldSummary != newSummary {
+		d.report(KindChanged, false, "description changed")
+	}
+
+	if oldTags, newTags := getStrings(old.value, "tags"), getStrings(new.value, "tags"); !slices.Equal(oldTags, newTags) {
+		d.report(KindChanged, false, "tags changed from [%v] to [%v]", strings.Join(oldTags, ", "), strings.Join(newTags, ", "))
+	}
+
+	d.params(old, new)
+	d.requestBody(getObject(old.value, "requestBody"), getObject(new.value, "requestBody"))
+	d.responses(getObject(old.value, "responses"), getObject(new.value, "responses"))
+	d.security(old.value, new.value)
+}
+
+// params returns parameters of operation by key, path params are keyed by
+// their position in the path
+func params(op operation) (map[string]object, map[string]string) {
+	out, labels := make(map[string]object), make(map[string]string)
+
+	for _, item := range getList(op.value, "parameters") {
+		param, _ := item.(map[string]any)
+		name, in := getString(param, "name"), getString(param, "in")
+
+		key := in + " " + name
+		if idx := slices.Index(op.pathParams, name); in == "path" && idx >= 0 {
+			key = fmt.Sprintf("path %d", idx)
+		}
+
+		out[key] = param
+		labels[key] = fmt.Sprintf("%v param %v", in, name)
+	}
+
+	return out, labels
+}
+
+func (d *differ) params(old, new operation) {
+	oldParams, oldLabels := params(old)
+	newParams, newLabels := params(new)
+
+	for _, key := range slices.Sorted(maps.Keys(oldParams)) {
+		oldParam := oldParams[key]
+		newParam, has := newParams[key]
+
+		if !has {
+			d.report(KindRemoved, d.direction(request) == request, "%v removed", oldLabels[key])
+			continue
+		}
+
+		if oldLabels[key] != newLabels[key] {
+			d.report(KindChanged, false, "%v renamed to %v", oldLabels[key], getString(newParam, "name"))
+		}
+
+		switch oldRequired, newRequired := getBool(oldParam, "required"), getBool(newParam, "required"); {
+		case !oldRequired && newRequired:
+			d.report(KindChanged, d.direction(request) == request, "%v is now required", newLabels[key])
+		case oldRequired && !newRequired:
+			d.report(KindChanged, d.direction(request) == response, "%v is now optional", newLabels[key])
+		}
+
+		d.schema(newLabels[key], "", oldParam["schema"], newParam["schema"], d.direction(request))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(newParams)) {
+		if _, has := oldParams[key]; has {
+			continue
+		}
+
+		required := getBool(newParams[key], "required")
+		if required {
+			d.report(KindAdded, d.direction(request) == request, "required %v added", newLabels[key])
+		} else {
+			d.report(KindAdded, false, "optional %v added", newLabels[key])
+		}
+	}
+}
+
+func (d *differ) requestBody(old, new object) {
+	dir := d.direction(request)
+
+	switch {
+	case old == nil && new == nil:
+		return
+	case old == nil:
+		d.report(KindAdded, getBool(new, "required") && dir == request, "request body added")
+		return
+	case new == nil:
+		d.report(KindRemoved, dir == request, "request body removed")
+		return
+	}
+
+	if !getBool(old, "required") && getBool(new, "required") {
+		d.report(KindChanged, dir == request, "request body is now required")
+	}
+
+	d.content("request body", getObject(old, "content"), getObject(new, "content"), dir)
+}
+
+// content compares schemas of media types, media types which are no longer
+// accepted or returned are breaking
+func (d *differ) content(where string, old, new object, dir direction) {
+	for _, mediaType := range slices.Sorted(maps.Keys(old)) {
+		newMedia, has := new[mediaType]
+		if !has {
+			d.report(KindRemoved, true, "%v %v removed", where, mediaType)
+			continue
+		}
+
+		oldMedia, _ := old[mediaType].(map[string]any)
+		newMediaObject, _ := newMedia.(map[string]any)
+		d.schema(where+" "+mediaType, "", oldMedia["schema"], newMediaObject["schema"], dir)
+	}
+
+	for _, mediaType := range slices.Sorted(maps.Keys(new)) {
+		if _, has := old[mediaType]; !has {
+			d.report(KindAdded, false, "%v %v added", where, mediaType)
+		}
+	}
+}
+
+func (d *differ) responses(old, new object) {
+	dir := d.direction(response)
+
+	for _, code := range slices.Sorted(maps.Keys(old)) {
+		where := "response " + code
+
+		newResponse := getObject(new, code)
+		if newResponse == nil {
+			d.report(KindRemoved, dir == response, "%v removed", where)
+			continue
+		}
+		oldResponse := getObject(old, code)
+
+		d.content(where, getObject(oldResponse, "content"), getObject(newResponse, "content"), dir)
+
+		oldHeaders, newHeaders := getObject(oldResponse, "headers"), getObject(newResponse, "headers")
+		for _, name := range slices.Sorted(maps.Keys(oldHeaders)) {
+			header := where + " header " + name
+			newHeader := getObject(newHeaders, name)
+			if newHeader == nil {
+				d.report(KindRemoved, dir == response, "%v removed", header)
+				continue
+			}
+			d.schema(header, "", getObject(oldHeaders, name)["schema"], newHeader["schema"], dir)
+		}
+		for _, name := range slices.Sorted(maps.Keys(newHeaders)) {
+			if _, has := oldHeaders[name]; !has {
+				d.report(KindAdded, false, "%v header %v added", where, name)
+			}
+		}
+	}
+
+	for _, code := range slices.Sorted(maps.Keys(new)) {
+		if _, has := old[code]; !has {
+			d.report(KindAdded, false, "response %v added", code)
+		}
+	}
+}
+
+// requirements returns security requirements of operation as sorted
+// canonical JSON, empty requirement {} allows anonymous access
+func requirements(op object) []string {
+	var out []string
+	for _, requirement := range getList(op, "security") {
+		data, _ := json.Marshal(requirement)
+		out = append(out, string(data))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func anonymous(requirements []string) bool {
+	return len(requirements) == 0 || slices.Contains(requirements, "{}")
+}
+
+func (d *differ) security(old, new object) {
+	oldRequirements, newRequirements := requirements(old), requirements(new)
+
+	switch {
+	case slices.Equal(oldRequirements, newRequirements):
+		return
+	case anonymous(oldRequirements) && !anonymous(newRequirements):
+		d.report(KindChanged, true, "authentication is now required")
+	case !anonymous(oldRequirements) && anonymous(newRequirements):
+		d.report(KindChanged, false, "authentication is no longer required")
+	}
+
+	for _, requirement := range oldRequirements {
+		if requirement != "{}" && !slices.Contains(newRequirements, requirement) {
+			d.report(KindChanged, !anonymous(newRequirements), "security requirement %v removed", requirement)
+		}
+	}
+	for _, requirement := range newRequirements {
+		if requirement != "{}" && !slices.Contains(oldRequirements, requirement) {
+			d.report(KindChanged, false, "security requirement %v added", requirement)
+		}
+	}
+}
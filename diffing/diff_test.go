@@ -0,0 +1,333 @@
+package diffing_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/diffing"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+const header = `info:
+  title: Diff
+  version: "1"
+servers:
+  - url: /
+`
+
+// compile compiles body of qapi document, failing on any diagnostics
+func compile(t *testing.T, body string) *compilation.Document {
+	t.Helper()
+
+	var in docs.Document
+	if err := yaml.Unmarshal([]byte(header+body), &in); err != nil {
+		t.Fatal(err)
+	}
+
+	var out compilation.Document
+	if diagnostics, _ := compilation.Compile(&out, &in, compilation.Options{}); len(diagnostics) != 0 {
+		t.Fatalf("compile: %v", diagnostics)
+	}
+	return &out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []string
+	}{
+		{
+			name: "operation removed",
+			old: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+    delete:
+      responses:
+        204:
+          description: ok
+`,
+			new: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+`,
+			want: []string{"breaking DELETE /users: operation removed"},
+		},
+		{
+			name: "operation added",
+			old: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+`,
+			new: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+    post:
+      responses:
+        201:
+          description: ok
+`,
+			want: []string{"non-breaking POST /users: operation added"},
+		},
+		{
+			name: "params added",
+			old: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+`,
+			new: `paths:
+  /users:
+    get:
+      params:
+        - name: limit
+          schema: integer
+          required: false
+        - name: team
+          schema: string
+          required: true
+      responses:
+        200:
+          description: ok
+`,
+			want: []string{
+				"non-breaking GET /users: optional query param limit added",
+				"breaking GET /users: required query param team added",
+			},
+		},
+		{
+			name: "path param renamed",
+			old: `paths:
+  /users/{id}:
+    get:
+      params:
+        - name: id
+          schema: integer
+          required: true
+      responses:
+        200:
+          description: ok
+`,
+			new: `paths:
+  /users/{userId}:
+    get:
+      params:
+        - name: userId
+          schema: integer
+          required: true
+      responses:
+        200:
+          description: ok
+`,
+			want: []string{"non-breaking GET /users/{userId}: path param id renamed to userId"},
+		},
+		{
+			name: "schema narrowed",
+			old: `schemas:
+  User:
+    name: string(1:64)
+paths:
+  /users:
+    post:
+      body:
+        application/json: <User>
+      responses:
+        201:
+          description: ok
+          application/json: <User>
+`,
+			new: `schemas:
+  User:
+    name: string(1:32)
+paths:
+  /users:
+    post:
+      body:
+        application/json: <User>
+      responses:
+        201:
+          description: ok
+          application/json: <User>
+`,
+			want: []string{
+				"breaking POST /users: request body application/json field name maximum changed from 64 to 32",
+				"non-breaking POST /users: response 201 application/json field name maximum changed from 64 to 32",
+			},
+		},
+		{
+			name: "schema used by two fields",
+			old: `schemas:
+  Address:
+    city: string(1:64)
+paths:
+  /orders:
+    post:
+      body:
+        application/json:
+          billing: <Address>
+          shipping: <Address>
+      responses:
+        201:
+          description: ok
+`,
+			new: `schemas:
+  Address:
+    city: string(1:32)
+paths:
+  /orders:
+    post:
+      body:
+        application/json:
+          billing: <Address>
+          shipping: <Address>
+      responses:
+        201:
+          description: ok
+`,
+			want: []string{
+				"breaking POST /orders: request body application/json field billing.city maximum changed from 64 to 32",
+				"breaking POST /orders: request body application/json field shipping.city maximum changed from 64 to 32",
+			},
+		},
+		{
+			name: "recursive schema",
+			old: `schemas:
+  Category:
+    name: string(1:64)
+    parent?: <Category>
+paths:
+  /categories:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json: <Category>
+`,
+			new: `schemas:
+  Category:
+    name: string(1:32)
+    parent?: <Category>
+paths:
+  /categories:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json: <Category>
+`,
+			want: []string{
+				"non-breaking GET /categories: response 200 application/json field name maximum changed from 64 to 32",
+			},
+		},
+		{
+			name: "response field removed",
+			old: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json:
+            id: integer
+            name: string
+`,
+			new: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+          application/json:
+            id: integer
+            email: string
+`,
+			want: []string{
+				"breaking GET /users: response 200 application/json field name removed",
+				"non-breaking GET /users: required response 200 application/json field email added",
+			},
+		},
+		{
+			name: "operation deprecated",
+			old: `paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+`,
+			new: `paths:
+  /users:
+    get:
+      deprecated: true
+      responses:
+        200:
+          description: ok
+`,
+			want: []string{"non-breaking GET /users: operation deprecated"},
+		},
+		{
+			name: "webhook payload narrowed",
+			old: `webhooks:
+  userCreated:
+    post:
+      body:
+        application/json:
+          id: integer
+      responses:
+        200:
+          description: ok
+`,
+			new: `webhooks:
+  userCreated:
+    post:
+      body:
+        application/json:
+          id: integer
+          name: string
+      responses:
+        200:
+          description: ok
+`,
+			want: []string{"non-breaking POST webhook userCreated: required request body application/json field name added"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := diffing.Compare(compile(t, test.old), compile(t, test.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, change := range changes {
+				breaking := "non-breaking"
+				if change.Breaking {
+					breaking = "breaking"
+				}
+				got = append(got, fmt.Sprintf("%v %v: %v", breaking, change.Operation(), change.Message))
+			}
+
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got changes\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package diffing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Format of written changes
+type Format string
+
+const (
+	FormatText     Format = "text"
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatMarkdown, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, expected %v, %v or %v", s, FormatText, FormatMarkdown, FormatJSON)
+	}
+}
+
+// Write writes changes to w in format
+func Write(w io.Writer, changes []Change, format Format) error {
+	switch format {
+	case FormatMarkdown:
+		return writeMarkdown(w, changes)
+	case FormatJSON:
+		return writeJSON(w, changes)
+	default:
+		return writeText(w, changes)
+	}
+}
+
+func count(changes []Change) (breaking, other int) {
+	for _, change := range changes {
+		if change.Breaking {
+			breaking++
+		} else {
+			other++
+		}
+	}
+	return breaking, other
+}
+
+func writeText(w io.Writer, changes []Change) error {
+	for _, change := range changes {
+		label := "non-breaking"
+		if change.Breaking {
+			label = "breaking"
+		}
+		if _, err := fmt.Fprintf(w, "%v: %v: %v\n", label, change.Operation(), change.Message); err != nil {
+			return err
+		}
+	}
+
+	breaking, other := count(changes)
+	_, err := fmt.Fprintf(w, "%v breaking, %v non-breaking changes\n", breaking, other)
+	return err
+}
+
+func writeMarkdown(w io.Writer, changes []Change) error {
+	breaking, other := count(changes)
+
+	if breaking+other == 0 {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+
+	section := func(title string, n int, breaking bool) error {
+		if n == 0 {
+			return nil
+		}
+		if _, err := fmt.Fprintf(w, "## %v (%v)\n\n", title, n); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if change.Breaking != breaking {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "- `%v`: %v\n", change.Operation(), change.Message); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+
+	if err := section("Breaking changes", breaking, true); err != nil {
+		return err
+	}
+	return section("Non-breaking changes", other, false)
+}
+
+func writeJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+
+	breaking, other := count(changes)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Breaking    int      `json:"breaking"`
+		NonBreaking int      `json:"nonBreaking"`
+		Changes     []Change `json:"changes"`
+	}{breaking, other, changes})
+}
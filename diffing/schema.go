@@ -0,0 +1,269 @@
+package diffing
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	schemaRefPrefix = "#/components/schemas/"
+	maxRefDepth     = 32
+)
+
+// resolve follows references of schema to components of doc, name of the
+// first referenced schema is returned
+func resolve(doc object, value any) (object, string) {
+	schema, _ := value.(map[string]any)
+
+	var name string
+	for range maxRefDepth {
+		ref, ok := schema["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, schemaRefPrefix) {
+			break
+		}
+
+		if name == "" {
+			name = strings.TrimPrefix(ref, schemaRefPrefix)
+		}
+		schema = getObject(getObject(getObject(doc, "components"), "schemas"), strings.TrimPrefix(ref, schemaRefPrefix))
+	}
+
+	return schema, name
+}
+
+// unwrapNullable returns schema of oneOf [{type: null}, schema] and whether
+// it was nullable
+func unwrapNullable(schema object) (object, bool) {
+	if getBool(schema, "nullable") {
+		return schema, true
+	}
+
+	oneOf := getList(schema, "oneOf")
+	if len(oneOf) != 2 {
+		return schema, false
+	}
+
+	for i, item := range oneOf {
+		if variant, _ := item.(map[string]any); len(variant) == 1 && getString(variant, "type") == "null" {
+			other, _ := oneOf[1-i].(map[string]any)
+			return other, true
+		}
+	}
+
+	return schema, false
+}
+
+// breaks reports whether schema change is breaking, narrowed schemas reject
+// values they accepted before, which breaks requests, widened ones accept
+// more, which breaks clients reading responses
+func breaks(narrowed bool, dir direction) bool {
+	return narrowed == (dir == request)
+}
+
+// subject describes compared schema, at is path of property in it
+func subject(where, at string) string {
+	if at == "" {
+		return where
+	}
+	return where + " field " + at
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+func (d *differ) schema(where, at string, old, new any, dir direction) {
+	oldSchema, oldName := resolve(d.old, old)
+	newSchema, newName := resolve(d.new, new)
+
+	if oldName != "" && newName != "" {
+		// the same schema may be used by several fields, each is compared
+		key := fmt.Sprint(where, "|", at, "|", oldName, "|", newName, "|", dir)
+		pair := fmt.Sprint(where, "|", oldName, "|", newName, "|", dir)
+		if d.visited[key] || d.comparing[pair] {
+			return
+		}
+		d.visited[key] = true
+		d.comparing[pair] = true
+		defer delete(d.comparing, pair)
+	}
+
+	oldSchema, oldNullable := unwrapNullable(oldSchema)
+	newSchema, newNullable := unwrapNullable(newSchema)
+	oldSchema, _ = resolve(d.old, oldSchema)
+	newSchema, _ = resolve(d.new, newSchema)
+
+	what := subject(where, at)
+
+	if oldNullable != newNullable {
+		if newNullable {
+			d.report(KindChanged, breaks(false, dir), "%v is now nullable", what)
+		} else {
+			d.report(KindChanged, breaks(true, dir), "%v is no longer nullable", what)
+		}
+	}
+
+	oldType, newType := getString(oldSchema, "type"), getString(newSchema, "type")
+	if oldType != newType {
+		switch {
+		case oldType == "" || oldType == "number" && newType == "integer":
+			d.report(KindChanged, breaks(true, dir), "%v type changed from %v to %v", what, typeName(oldType), typeName(newType))
+		case newType == "" || oldType == "integer" && newType == "number":
+			d.report(KindChanged, breaks(false, dir), "%v type changed from %v to %v", what, typeName(oldType), typeName(newType))
+		default:
+			d.report(KindChanged, true, "%v type changed from %v to %v", what, typeName(oldType), typeName(newType))
+		}
+		return
+	}
+
+	if oldFormat, newFormat := getString(oldSchema, "format"), getString(newSchema, "format"); oldFormat != newFormat {
+		breaking := true
+		switch {
+		case oldFormat == "":
+			breaking = breaks(true, dir)
+		case newFormat == "":
+			breaking = breaks(false, dir)
+		}
+		d.report(KindChanged, breaking, "%v format changed from %v to %v", what, typeName(oldFormat), typeName(newFormat))
+	}
+
+	d.constraints(what, oldSchema, newSchema, dir)
+
+	if oldDefault, newDefault := fmt.Sprint(oldSchema["default"]), fmt.Sprint(newSchema["default"]); oldDefault != newDefault {
+		d.report(KindChanged, false, "%v default changed from %v to %v", what, valueName(oldSchema["default"]), valueName(newSchema["default"]))
+	}
+
+	switch newType {
+	case "object":
+		d.properties(where, at, oldSchema, newSchema, dir)
+	case "array":
+		d.schema(where, at+"[]", oldSchema["items"], newSchema["items"], dir)
+	}
+}
+
+func typeName(s string) string {
+	if s == "" {
+		return "any"
+	}
+	return s
+}
+
+func valueName(v any) string {
+	if v == nil {
+		return "none"
+	}
+	return fmt.Sprint(v)
+}
+
+// bounds of values, lower ones narrow schema when raised, upper ones when
+// lowered
+var (
+	lowerBounds = []string{"minimum", "minLength", "minItems"}
+	upperBounds = []string{"maximum", "maxLength", "maxItems"}
+)
+
+func (d *differ) constraints(what string, old, new object, dir direction) {
+	bound := func(key string, lower bool) {
+		oldValue, oldHas := old[key].(float64)
+		newValue, newHas := new[key].(float64)
+
+		var narrowed bool
+		switch {
+		case oldHas && newHas && oldValue == newValue, !oldHas && !newHas:
+			return
+		case !oldHas:
+			narrowed = true
+		case !newHas:
+			narrowed = false
+		default:
+			narrowed = (newValue > oldValue) == lower
+		}
+
+		d.report(KindChanged, breaks(narrowed, dir), "%v %v changed from %v to %v", what, key, valueName(old[key]), valueName(new[key]))
+	}
+
+	for _, key := range lowerBounds {
+		bound(key, true)
+	}
+	for _, key := range upperBounds {
+		bound(key, false)
+	}
+
+	if oldUnique, newUnique := getBool(old, "uniqueItems"), getBool(new, "uniqueItems"); oldUnique != newUnique {
+		d.report(KindChanged, breaks(newUnique, dir), "%v uniqueItems changed from %v to %v", what, oldUnique, newUnique)
+	}
+
+	_, oldHas := old["enum"]
+	_, newHas := new["enum"]
+	if !oldHas && !newHas {
+		return
+	}
+
+	values := func(o object) []string {
+		var out []string
+		for _, value := range getList(o, "enum") {
+			out = append(out, fmt.Sprint(value))
+		}
+		return out
+	}
+	oldValues, newValues := values(old), values(new)
+
+	switch {
+	case !oldHas:
+		d.report(KindChanged, breaks(true, dir), "%v is now limited to values %v", what, strings.Join(newValues, ", "))
+	case !newHas:
+		d.report(KindChanged, breaks(false, dir), "%v is no longer limited to enum values", what)
+	default:
+		for _, value := range oldValues {
+			if !slices.Contains(newValues, value) {
+				d.report(KindChanged, breaks(true, dir), "%v enum value %v removed", what, value)
+			}
+		}
+		for _, value := range newValues {
+			if !slices.Contains(oldValues, value) {
+				d.report(KindChanged, breaks(false, dir), "%v enum value %v added", what, value)
+			}
+		}
+	}
+}
+
+func (d *differ) properties(where, at string, old, new object, dir direction) {
+	oldProperties, newProperties := getObject(old, "properties"), getObject(new, "properties")
+	oldRequired, newRequired := getStrings(old, "required"), getStrings(new, "required")
+
+	for _, name := range slices.Sorted(maps.Keys(oldProperties)) {
+		what := subject(where, join(at, name))
+
+		if _, has := newProperties[name]; !has {
+			d.report(KindRemoved, breaks(false, dir), "%v removed", what)
+			continue
+		}
+
+		switch wasRequired, isRequired := slices.Contains(oldRequired, name), slices.Contains(newRequired, name); {
+		case !wasRequired && isRequired:
+			d.report(KindChanged, breaks(true, dir), "%v is now required", what)
+		case wasRequired && !isRequired:
+			d.report(KindChanged, breaks(false, dir), "%v is now optional", what)
+		}
+
+		d.schema(where, join(at, name), oldProperties[name], newProperties[name], dir)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(newProperties)) {
+		if _, has := oldProperties[name]; has {
+			continue
+		}
+
+		what := subject(where, join(at, name))
+		if slices.Contains(newRequired, name) {
+			d.report(KindAdded, breaks(true, dir), "required %v added", what)
+		} else {
+			d.report(KindAdded, false, "optional %v added", what)
+		}
+	}
+}
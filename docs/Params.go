@@ -1,9 +1,24 @@
 package docs
 
+import "github.com/goccy/go-yaml"
+
 type Param struct {
-	Name     string `yaml:"name"`
-	Schema   Schema `yaml:"schema"`
-	Required bool   `yaml:"required"`
+	Name       string     `yaml:"name"`
+	Schema     Schema     `yaml:"schema"`
+	Required   bool       `yaml:"required"`
+	Extensions Extensions `yaml:"-"`
 }
 
 type Params = []Param
+
+func (p *Param) UnmarshalYAML(data []byte) error {
+	type param Param
+
+	if err := yaml.Unmarshal(data, (*param)(p)); err != nil {
+		return err
+	}
+
+	ext, err := extractExtensions(data)
+	p.Extensions = ext
+	return err
+}
@@ -0,0 +1,16 @@
+package docs
+
+// DefaultGroup is the name of the group defined by the top-level
+// defaultResponses section.
+const DefaultGroup = "default"
+
+// DefaultResponsesPolicy selects default responses applied to a method or to
+// all methods nested under a path node.
+type DefaultResponsesPolicy struct {
+	// Groups replaces the inherited groups, an empty list disables defaults
+	Groups []string `yaml:"groups,omitempty"`
+	// Include adds groups to the inherited ones
+	Include []string `yaml:"include,omitempty"`
+	// Exclude removes status codes from the applied defaults
+	Exclude []StatusCode `yaml:"exclude,omitempty"`
+}
@@ -0,0 +1,143 @@
+package docs
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic is single problem found in the document
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Path     NodePath `json:"path,omitempty"`
+	Pos      Position `json:"position"`
+	Message  string   `json:"message"`
+}
+
+// NewDiagnostic creates diagnostic from error, location of *Error is kept
+func NewDiagnostic(severity Severity, err error) Diagnostic {
+	out := Diagnostic{
+		Severity: severity,
+		Message:  err.Error(),
+	}
+
+	var located *Error
+	if errors.As(err, &located) {
+		out.Path = located.Path
+		out.Pos = located.Pos
+		out.Message = located.Err.Error()
+	}
+
+	return out
+}
+
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+
+	if d.Pos.IsValid() {
+		fmt.Fprintf(&sb, "%v: ", d.Pos)
+	}
+
+	fmt.Fprintf(&sb, "%v: ", d.Severity)
+
+	if len(d.Path) != 0 {
+		fmt.Fprintf(&sb, "%v: ", d.Path)
+	}
+
+	sb.WriteString(d.Message)
+	return sb.String()
+}
+
+// Diagnostics is list of problems, it's used as error when contains errors
+type Diagnostics []Diagnostic
+
+// AsDiagnostics returns diagnostics of err, other errors are converted to
+// single error diagnostic
+func AsDiagnostics(err error) Diagnostics {
+	var diagnostics Diagnostics
+	if errors.As(err, &diagnostics) {
+		return diagnostics
+	}
+	return Diagnostics{NewDiagnostic(SeverityError, err)}
+}
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, diagnostic := range d {
+		lines[i] = diagnostic.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d Diagnostics) HasErrors() bool {
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns errors of the list, nil when there are none
+func (d Diagnostics) Err() error {
+	var errs Diagnostics
+	for _, diagnostic := range d {
+		if diagnostic.Severity == SeverityError {
+			errs = append(errs, diagnostic)
+		}
+	}
+
+	if errs == nil {
+		return nil
+	}
+	return errs
+}
+
+// Sort orders diagnostics by source file and position in it, unlocated ones
+// keep their order at the beginning
+func (d Diagnostics) Sort() {
+	slices.SortStableFunc(d, func(a, b Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.File, b.Pos.File),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+}
+
+// LocateAll resolves source positions of diagnostics in place, already
+// located ones are kept
+func (s *Source) LocateAll(d Diagnostics) {
+	for i := range d {
+		if !d[i].Pos.IsValid() {
+			d[i].Pos = s.Position(d[i].Path)
+		}
+	}
+}
@@ -1,11 +1,20 @@
 package docs
 
 type Document struct {
-	Info             Info              `yaml:"info"`
-	Servers          []Server          `yaml:"servers"`
-	Tags             []Tag             `yaml:"tags,omitempty"`
-	Schemas          map[string]Schema `yaml:"schemas,omitempty"`
-	Traits           Traits            `yaml:"traits,omitempty"`
-	DefaultResponses Responses         `yaml:"defaultResponses,omitempty"`
-	Paths            Paths             `yaml:"paths,omitempty"`
+	// files merged into the document, relative to it, may be directories
+	// or glob patterns
+	Imports []string          `yaml:"imports,omitempty"`
+	Info    Info              `yaml:"info"`
+	Servers []Server          `yaml:"servers"`
+	Tags    []Tag             `yaml:"tags,omitempty"`
+	Schemas map[string]Schema `yaml:"schemas,omitempty"`
+	// aliases of external schema files, e.g. shared for <shared:Money>
+	Externals        map[string]string         `yaml:"externals,omitempty"`
+	SecuritySchemes  map[string]SecurityScheme `yaml:"securitySchemes,omitempty"`
+	Traits           Traits                    `yaml:"traits,omitempty"`
+	DefaultResponses Responses                 `yaml:"defaultResponses,omitempty"`
+	// named groups of default responses, selected per path node or method
+	DefaultResponseGroups map[string]Responses `yaml:"defaultResponseGroups,omitempty"`
+	Paths                 Paths                `yaml:"paths,omitempty"`
+	Webhooks              Paths                `yaml:"webhooks,omitempty"`
 }
@@ -0,0 +1,80 @@
+package docs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Error is an error located in the source document
+type Error struct {
+	Path NodePath
+	// resolved by Source.Locate, invalid when unknown
+	Pos Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	var prefix string
+
+	switch {
+	case e.Pos.IsValid() && len(e.Path) != 0:
+		prefix = fmt.Sprintf("%v: %v: ", e.Pos, e.Path)
+	case e.Pos.IsValid():
+		prefix = fmt.Sprintf("%v: ", e.Pos)
+	case len(e.Path) != 0:
+		prefix = fmt.Sprintf("%v: ", e.Path)
+	}
+
+	return prefix + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// At prefixes path of located error with given segments, errors without
+// location are located at the segments.
+func At(err error, segments ...string) error {
+	if err == nil {
+		return nil
+	}
+
+	var located *Error
+	if errors.As(err, &located) {
+		located.Path = slices.Concat(NodePath(segments), located.Path)
+		return err
+	}
+
+	return &Error{
+		Path: NodePath(segments),
+		Err:  err,
+	}
+}
+
+// Errorf creates error located at path
+func Errorf(path NodePath, format string, args ...any) error {
+	return &Error{
+		Path: path,
+		Err:  fmt.Errorf(format, args...),
+	}
+}
+
+// Locate resolves source position of located error or diagnostics, which
+// are also sorted by it, other errors and positions resolved before are
+// returned unchanged.
+func (s *Source) Locate(err error) error {
+	var diagnostics Diagnostics
+	if errors.As(err, &diagnostics) {
+		s.LocateAll(diagnostics)
+		diagnostics.Sort()
+		return err
+	}
+
+	var located *Error
+	if errors.As(err, &located) && !located.Pos.IsValid() {
+		located.Pos = s.Position(located.Path)
+	}
+
+	return err
+}
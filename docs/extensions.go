@@ -0,0 +1,42 @@
+package docs
+
+import (
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Extensions holds vendor extension keys (x-*), passed verbatim to the
+// compiled document.
+type Extensions map[string]any
+
+const ExtensionPrefix = "x-"
+
+func IsExtension(key string) bool {
+	return strings.HasPrefix(key, ExtensionPrefix)
+}
+
+// extractExtensions collects all x-* keys of a YAML mapping.
+func extractExtensions(data []byte) (Extensions, error) {
+	var raw yaml.MapSlice
+
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	var out Extensions
+
+	for _, item := range raw {
+		key, ok := item.Key.(string)
+		if !ok || !IsExtension(key) {
+			continue
+		}
+
+		if out == nil {
+			out = make(Extensions)
+		}
+		out[key] = item.Value
+	}
+
+	return out, nil
+}
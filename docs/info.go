@@ -1,7 +1,22 @@
 package docs
 
+import "github.com/goccy/go-yaml"
+
 type Info struct {
-	Title       string `yaml:"title"`
-	Version     string `yaml:"version"`
-	Description string `yaml:"description,omitempty"`
+	Title       string     `yaml:"title"`
+	Version     string     `yaml:"version"`
+	Description string     `yaml:"description,omitempty"`
+	Extensions  Extensions `yaml:"-"`
+}
+
+func (i *Info) UnmarshalYAML(data []byte) error {
+	type info Info
+
+	if err := yaml.Unmarshal(data, (*info)(i)); err != nil {
+		return err
+	}
+
+	ext, err := extractExtensions(data)
+	i.Extensions = ext
+	return err
 }
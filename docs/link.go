@@ -0,0 +1,12 @@
+package docs
+
+// Link describes how a value returned by a response can be used as input of
+// other operation, referenced by its qapi id.
+type Link struct {
+	Operation   string         `yaml:"operation"`
+	Params      map[string]any `yaml:"params,omitempty"`
+	Body        any            `yaml:"body,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+}
+
+type Links = map[string]Link
@@ -1,11 +1,33 @@
 package docs
 
+import "github.com/goccy/go-yaml"
+
 type Method struct {
-	Id          string      `yaml:"id,omitempty"`
-	Description string      `yaml:"description,omitempty"`
-	Traits      []string    `yaml:"traits,omitempty"`
-	Params      Params      `yaml:"params,omitempty"`
-	Headers     Params      `yaml:"headers,omitempty"`
-	Body        TypedSchema `yaml:"body,omitempty"`
-	Responses   Responses   `yaml:"responses,omitempty"`
+	Id          string                `yaml:"id,omitempty"`
+	Description string                `yaml:"description,omitempty"`
+	Deprecated  bool                  `yaml:"deprecated,omitempty"`
+	Traits      []string              `yaml:"traits,omitempty"`
+	Params      Params                `yaml:"params,omitempty"`
+	Headers     Params                `yaml:"headers,omitempty"`
+	Body        TypedSchema           `yaml:"body,omitempty"`
+	Responses   Responses             `yaml:"responses,omitempty"`
+	Security    []SecurityRequirement `yaml:"security,omitempty"`
+	Callbacks   map[string]Callback   `yaml:"callbacks,omitempty"`
+
+	DefaultResponses *DefaultResponsesPolicy `yaml:"defaultResponses,omitempty"`
+	// idents of traits inherited from path nodes, which are not applied
+	ExcludeTraits []string   `yaml:"excludeTraits,omitempty"`
+	Extensions    Extensions `yaml:"-"`
+}
+
+func (m *Method) UnmarshalYAML(data []byte) error {
+	type method Method
+
+	if err := yaml.Unmarshal(data, (*method)(m)); err != nil {
+		return err
+	}
+
+	ext, err := extractExtensions(data)
+	m.Extensions = ext
+	return err
 }
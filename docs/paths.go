@@ -3,17 +3,25 @@ package docs
 import "github.com/goccy/go-yaml"
 
 type Path struct {
-	Tags   []string        `yaml:"tags,omitempty"`
-	Get    *Method         `yaml:"get,omitempty"`
-	Post   *Method         `yaml:"post,omitempty"`
-	Put    *Method         `yaml:"put,omitempty"`
-	Patch  *Method         `yaml:"patch,omitempty"`
-	Delete *Method         `yaml:"delete,omitempty"`
-	Nested map[string]Path `yaml:",inline"`
+	// file whose paths are nested in the node, relative to the document
+	Mount            string                  `yaml:"mount,omitempty"`
+	Tags             []string                `yaml:"tags,omitempty"`
+	DefaultResponses *DefaultResponsesPolicy `yaml:"defaultResponses,omitempty"`
+	Traits           PathTraits              `yaml:"traits,omitempty"`
+	Get              *Method                 `yaml:"get,omitempty"`
+	Post             *Method                 `yaml:"post,omitempty"`
+	Put              *Method                 `yaml:"put,omitempty"`
+	Patch            *Method                 `yaml:"patch,omitempty"`
+	Delete           *Method                 `yaml:"delete,omitempty"`
+	Extensions       Extensions              `yaml:"-"`
+	Nested           map[string]Path         `yaml:",inline"`
 }
 
 type Paths = map[string]Path
 
+// Callback maps runtime expression (e.g. {$request.body#/callbackUrl}) to path
+type Callback = map[string]Path
+
 func (p *Path) UnmarshalYAML(bytes []byte) error {
 
 	var raw map[string]yaml.RawMessage
@@ -29,6 +37,28 @@ func (p *Path) UnmarshalYAML(bytes []byte) error {
 		delete(raw, "tags")
 	}
 
+	if mount, has := raw["mount"]; has {
+		if err := yaml.Unmarshal(mount, &p.Mount); err != nil {
+			return err
+		}
+		delete(raw, "mount")
+	}
+
+	if traits, has := raw["traits"]; has {
+		if err := yaml.Unmarshal(traits, &p.Traits); err != nil {
+			return err
+		}
+		delete(raw, "traits")
+	}
+
+	if defaults, has := raw["defaultResponses"]; has {
+		p.DefaultResponses = new(DefaultResponsesPolicy)
+		if err := yaml.Unmarshal(defaults, p.DefaultResponses); err != nil {
+			return err
+		}
+		delete(raw, "defaultResponses")
+	}
+
 	if get, has := raw["get"]; has {
 		p.Get = new(Method)
 		if err := yaml.Unmarshal(get, p.Get); err != nil {
@@ -69,6 +99,23 @@ func (p *Path) UnmarshalYAML(bytes []byte) error {
 		delete(raw, "delete")
 	}
 
+	for k, v := range raw {
+		if !IsExtension(k) {
+			continue
+		}
+
+		if p.Extensions == nil {
+			p.Extensions = make(Extensions)
+		}
+
+		var value any
+		if err := yaml.Unmarshal(v, &value); err != nil {
+			return err
+		}
+		p.Extensions[k] = value
+		delete(raw, k)
+	}
+
 	if len(raw) == 0 {
 		return nil
 	} else {
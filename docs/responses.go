@@ -5,7 +5,10 @@ import (
 )
 
 type Response struct {
-	Description string `yaml:"description"`
+	Description string     `yaml:"description"`
+	Headers     Params     `yaml:"headers,omitempty"`
+	Links       Links      `yaml:"links,omitempty"`
+	Extensions  Extensions `yaml:"-"`
 	TypedSchema `yaml:"-"`
 }
 
@@ -30,6 +33,42 @@ func (r *Response) UnmarshalYAML(data []byte) error {
 		delete(raw, "description")
 	}
 
+	// Extract headers
+	if headers, ok := raw["headers"]; ok {
+		if err := yaml.Unmarshal(headers, &r.Headers); err != nil {
+			return err
+		}
+
+		delete(raw, "headers")
+	}
+
+	// Extract links
+	if links, ok := raw["links"]; ok {
+		if err := yaml.Unmarshal(links, &r.Links); err != nil {
+			return err
+		}
+
+		delete(raw, "links")
+	}
+
+	// Extract vendor extensions
+	for key, value := range raw {
+		if !IsExtension(key) {
+			continue
+		}
+
+		if r.Extensions == nil {
+			r.Extensions = make(Extensions)
+		}
+
+		var out any
+		if err := yaml.Unmarshal(value, &out); err != nil {
+			return err
+		}
+		r.Extensions[key] = out
+		delete(raw, key)
+	}
+
 	// Initialize the map if needed
 	if r.TypedSchema == nil {
 		r.TypedSchema = make(TypedSchema)
@@ -17,6 +17,8 @@ type Properties []Property
 
 type Schema struct {
 	Value any
+	// vendor extensions, only for object definitions
+	Extensions Extensions
 }
 
 func (s *Schema) UnmarshalYAML(data []byte) error {
@@ -43,6 +45,14 @@ func (s *Schema) UnmarshalYAML(data []byte) error {
 			return fmt.Errorf("property key must be a string, got %T", item.Key)
 		}
 
+		if IsExtension(name) {
+			if s.Extensions == nil {
+				s.Extensions = make(Extensions)
+			}
+			s.Extensions[name] = item.Value
+			continue
+		}
+
 		// Marshal the value back to YAML and unmarshal into Schema
 		valueBytes, err := yaml.Marshal(item.Value)
 		if err != nil {
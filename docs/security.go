@@ -0,0 +1,16 @@
+package docs
+
+// SecurityScheme maps to OpenAPI security scheme object.
+type SecurityScheme struct {
+	Type             string         `yaml:"type"`
+	Description      string         `yaml:"description,omitempty"`
+	Name             string         `yaml:"name,omitempty"`
+	In               string         `yaml:"in,omitempty"`
+	Scheme           string         `yaml:"scheme,omitempty"`
+	BearerFormat     string         `yaml:"bearerFormat,omitempty"`
+	Flows            map[string]any `yaml:"flows,omitempty"`
+	OpenIdConnectUrl string         `yaml:"openIdConnectUrl,omitempty"`
+}
+
+// SecurityRequirement maps security scheme name to required scopes.
+type SecurityRequirement = map[string][]string
@@ -1,6 +1,21 @@
 package docs
 
+import "github.com/goccy/go-yaml"
+
 type Server struct {
-	Url         string `yaml:"url"`
-	Description string `yaml:"description,omitempty"`
+	Url         string     `yaml:"url"`
+	Description string     `yaml:"description,omitempty"`
+	Extensions  Extensions `yaml:"-"`
+}
+
+func (s *Server) UnmarshalYAML(data []byte) error {
+	type server Server
+
+	if err := yaml.Unmarshal(data, (*server)(s)); err != nil {
+		return err
+	}
+
+	ext, err := extractExtensions(data)
+	s.Extensions = ext
+	return err
 }
@@ -0,0 +1,208 @@
+package docs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// NodePath locates node in source document, as keys of mappings and indexes
+// of sequences, e.g. paths./api/v1./events.get.responses.200
+type NodePath []string
+
+func (p NodePath) String() string {
+	return strings.Join(p, ".")
+}
+
+// Position in source file, lines and columns start at 1
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%v:%v", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+}
+
+// Source keeps syntax tree of document, used to find positions of nodes
+type Source struct {
+	File   string
+	root   ast.Node
+	mounts []mount
+}
+
+// mount locates nodes under path in other source, under at
+type mount struct {
+	path   NodePath
+	source *Source
+	at     NodePath
+}
+
+// Mount makes node at path and nodes under it located in source at given
+// path, used for documents merged from several files
+func (s *Source) Mount(path NodePath, source *Source, at NodePath) {
+	s.mounts = append(s.mounts, mount{
+		path:   slices.Clone(path),
+		source: source,
+		at:     slices.Clone(at),
+	})
+}
+
+// Files returns file of source followed by files of sources mounted in it
+func (s *Source) Files() []string {
+	out := []string{s.File}
+
+	for _, m := range s.mounts {
+		for _, file := range m.source.Files() {
+			if !slices.Contains(out, file) {
+				out = append(out, file)
+			}
+		}
+	}
+
+	return out
+}
+
+// mountOf returns the most specific mount containing path
+func (s *Source) mountOf(path NodePath) (mount, bool) {
+	var (
+		found mount
+		ok    bool
+	)
+
+	for _, m := range s.mounts {
+		if len(m.path) > len(path) || !slices.Equal(m.path, path[:len(m.path)]) {
+			continue
+		}
+		if !ok || len(m.path) > len(found.path) {
+			found, ok = m, true
+		}
+	}
+
+	return found, ok
+}
+
+func ParseSource(file string, data []byte) (*Source, error) {
+	tree, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Source{
+		File: file,
+	}
+
+	if len(tree.Docs) != 0 {
+		out.root = tree.Docs[0].Body
+	}
+
+	return out, nil
+}
+
+func unwrapNode(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		case *ast.AliasNode:
+			return n
+		default:
+			return node
+		}
+	}
+}
+
+func keyString(key ast.MapKeyNode) string {
+	if k, ok := key.(*ast.MappingKeyNode); ok {
+		return k.Value.GetToken().Value
+	}
+	return key.GetToken().Value
+}
+
+func nodePosition(file string, node ast.Node) Position {
+	// tokens of mappings are their first ':', position of first key is used
+	switch n := unwrapNode(node).(type) {
+	case *ast.MappingNode:
+		if len(n.Values) != 0 {
+			node = n.Values[0].Key
+		}
+	case *ast.MappingValueNode:
+		node = n.Key
+	}
+
+	tk := node.GetToken()
+	if tk == nil || tk.Position == nil {
+		return Position{File: file}
+	}
+	return Position{
+		File:   file,
+		Line:   tk.Position.Line,
+		Column: tk.Position.Column,
+	}
+}
+
+// Position returns position of node at path, for mapping values position of
+// its key. When path doesn't exist whole, position of the deepest existing
+// node is returned.
+func (s *Source) Position(path NodePath) Position {
+	if s == nil {
+		return Position{}
+	}
+
+	if m, ok := s.mountOf(path); ok {
+		return m.source.Position(slices.Concat(m.at, path[len(m.path):]))
+	}
+
+	if s.root == nil {
+		return Position{File: s.File}
+	}
+
+	var found ast.Node = s.root
+	node := s.root
+
+	for _, segment := range path {
+		var next ast.Node
+
+		switch n := unwrapNode(node).(type) {
+		case *ast.MappingNode:
+			for _, value := range n.Values {
+				if keyString(value.Key) == segment {
+					found, next = value.Key, value.Value
+					break
+				}
+			}
+		case *ast.MappingValueNode:
+			if keyString(n.Key) == segment {
+				found, next = n.Key, n.Value
+			}
+		case *ast.SequenceNode:
+			if idx, err := strconv.Atoi(segment); err == nil && idx >= 0 && idx < len(n.Values) {
+				found, next = n.Values[idx], n.Values[idx]
+			}
+		}
+
+		if next == nil {
+			break
+		}
+		node = next
+	}
+
+	return nodePosition(s.File, found)
+}
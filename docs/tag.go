@@ -1,6 +1,21 @@
 package docs
 
+import "github.com/goccy/go-yaml"
+
 type Tag struct {
-	Name        string `yaml:"name"`
-	Description string `yaml:"description,omitempty"`
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description,omitempty"`
+	Extensions  Extensions `yaml:"-"`
+}
+
+func (t *Tag) UnmarshalYAML(data []byte) error {
+	type tag Tag
+
+	if err := yaml.Unmarshal(data, (*tag)(t)); err != nil {
+		return err
+	}
+
+	ext, err := extractExtensions(data)
+	t.Extensions = ext
+	return err
 }
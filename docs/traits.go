@@ -1,8 +1,77 @@
 package docs
 
+import (
+	"slices"
+
+	"github.com/goccy/go-yaml"
+)
+
 type Trait struct {
-	Params  Params `yaml:"params,omitempty"`
-	Headers Params `yaml:"headers,omitempty"`
+	// included traits, may pass own arguments, e.g. paged(#Max)
+	Traits  []string `yaml:"traits,omitempty"`
+	Params  Params   `yaml:"params,omitempty"`
+	Headers Params   `yaml:"headers,omitempty"`
+	// headers added to every 2XX response of the method
+	ResponseHeaders Params                `yaml:"responseHeaders,omitempty"`
+	Body            TypedSchema           `yaml:"body,omitempty"`
+	Responses       Responses             `yaml:"responses,omitempty"`
+	Tags            []string              `yaml:"tags,omitempty"`
+	Security        []SecurityRequirement `yaml:"security,omitempty"`
 }
 
 type Traits = map[string]Trait
+
+// PathTraits are trait invocations declared on path node, inherited by all
+// nested methods. Declared either as a list applied to every method or as
+// a map keyed by method name, where "all" applies to every method.
+type PathTraits struct {
+	All    []string `yaml:"all,omitempty"`
+	Get    []string `yaml:"get,omitempty"`
+	Post   []string `yaml:"post,omitempty"`
+	Put    []string `yaml:"put,omitempty"`
+	Patch  []string `yaml:"patch,omitempty"`
+	Delete []string `yaml:"delete,omitempty"`
+}
+
+func (t *PathTraits) UnmarshalYAML(data []byte) error {
+	var all []string
+	if err := yaml.Unmarshal(data, &all); err == nil {
+		t.All = all
+		return nil
+	}
+
+	type pathTraits PathTraits
+	return yaml.Unmarshal(data, (*pathTraits)(t))
+}
+
+// Inherit returns parent's traits followed by t's, per method
+func (t PathTraits) Inherit(parent PathTraits) PathTraits {
+	return PathTraits{
+		All:    slices.Concat(parent.All, t.All),
+		Get:    slices.Concat(parent.Get, t.Get),
+		Post:   slices.Concat(parent.Post, t.Post),
+		Put:    slices.Concat(parent.Put, t.Put),
+		Patch:  slices.Concat(parent.Patch, t.Patch),
+		Delete: slices.Concat(parent.Delete, t.Delete),
+	}
+}
+
+// ForMethod returns traits applied to given method, "all" ones first
+func (t PathTraits) ForMethod(method string) []string {
+	var specific []string
+
+	switch method {
+	case "get":
+		specific = t.Get
+	case "post":
+		specific = t.Post
+	case "put":
+		specific = t.Put
+	case "patch":
+		specific = t.Patch
+	case "delete":
+		specific = t.Delete
+	}
+
+	return slices.Concat(t.All, specific)
+}
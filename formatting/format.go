@@ -0,0 +1,535 @@
+package formatting
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// indentation of nested nodes
+const indentWidth = 2
+
+type Options struct {
+	// Order of schemas, other entries keep order of source
+	Order compilation.Order
+}
+
+// Format rewrites qapi document in canonical layout. Keys of known sections
+// are ordered, scalars are quoted only when they have to and schema
+// expressions lose whitespace. Comments are kept.
+func Format(data []byte, opts Options) ([]byte, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &printer{opts: opts}
+
+	for i, doc := range file.Docs {
+		if i != 0 {
+			p.buf.WriteString("---\n")
+		}
+
+		p.comments(doc.GetComment(), 0)
+
+		if doc.Body != nil {
+			p.header(doc.Body)
+			p.block(doc.Body, rootKind, 0)
+		}
+	}
+
+	return p.buf.Bytes(), nil
+}
+
+type printer struct {
+	buf  bytes.Buffer
+	opts Options
+	// head comment lines of entries already written as document comment
+	written map[*ast.MappingValueNode]int
+}
+
+func (p *printer) line(indent int, text string) {
+	p.buf.WriteString(strings.Repeat(" ", indent))
+	p.buf.WriteString(text)
+	p.buf.WriteByte('\n')
+}
+
+// comments writes lines of comment group at indent
+func (p *printer) comments(group *ast.CommentGroupNode, indent int) {
+	for _, comment := range commentLines(group) {
+		p.line(indent, comment)
+	}
+}
+
+func commentLines(group *ast.CommentGroupNode) []string {
+	if group == nil {
+		return nil
+	}
+
+	var out []string
+	for _, line := range strings.Split(group.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// header writes comments of the first entry of document separated from it
+// by blank line, they describe the document and stay on top of it
+func (p *printer) header(body ast.Node) {
+	var first *ast.MappingValueNode
+
+	switch body := body.(type) {
+	case *ast.MappingNode:
+		if len(body.Values) != 0 {
+			first = body.Values[0]
+		}
+	case *ast.MappingValueNode:
+		first = body
+	}
+
+	if first == nil || first.GetComment() == nil {
+		return
+	}
+
+	comments := first.GetComment().Comments
+	line := first.Key.GetToken().Position.Line
+
+	// comments directly above the key belong to it
+	attached := len(comments)
+	for attached > 0 && comments[attached-1].Token.Position.Line == line-1 {
+		attached--
+		line--
+	}
+
+	if attached == 0 {
+		return
+	}
+
+	for _, comment := range comments[:attached] {
+		p.line(0, strings.TrimSpace(comment.String()))
+	}
+	p.buf.WriteByte('\n')
+
+	p.written = map[*ast.MappingValueNode]int{first: attached}
+}
+
+// lineComment returns comment following node on its line, with separator
+func lineComment(n ast.Node) string {
+	if lines := commentLines(n.GetComment()); len(lines) != 0 {
+		return " " + strings.Join(lines, " ")
+	}
+	return ""
+}
+
+// block writes collection n at indent, other nodes are written as single
+// line
+func (p *printer) block(n ast.Node, k kind, indent int) {
+	switch n := n.(type) {
+	case *ast.MappingNode:
+		p.mapping(n, k, indent)
+	case *ast.MappingValueNode:
+		p.mapping(&ast.MappingNode{Values: []*ast.MappingValueNode{n}}, k, indent)
+	case *ast.SequenceNode:
+		p.sequence(n, k, indent)
+	default:
+		text, _ := p.inline(n, k, indent)
+		p.line(indent, text)
+	}
+}
+
+// entries returns entries of mapping of kind k, in order the kind declares
+func (p *printer) entries(n *ast.MappingNode, k kind) []*ast.MappingValueNode {
+	out := slices.Clone(n.Values)
+	r := rules[k]
+
+	if r.sortable && p.opts.Order == compilation.OrderSorted {
+		slices.SortStableFunc(out, func(a, b *ast.MappingValueNode) int {
+			return cmp.Compare(keyOf(a), keyOf(b))
+		})
+	}
+
+	if len(r.order) == 0 {
+		return out
+	}
+
+	rank := func(e *ast.MappingValueNode) int {
+		key := keyOf(e)
+		if idx := slices.Index(r.order, key); idx != -1 {
+			return idx
+		}
+		if docs.IsExtension(key) {
+			return slices.Index(r.order, "x-")
+		}
+		return slices.Index(r.order, "*")
+	}
+
+	slices.SortStableFunc(out, func(a, b *ast.MappingValueNode) int {
+		return cmp.Compare(rank(a), rank(b))
+	})
+
+	return out
+}
+
+// keyOf returns key of mapping entry as written
+func keyOf(e *ast.MappingValueNode) string {
+	var key ast.Node = e.Key
+	if k, ok := key.(*ast.MappingKeyNode); ok {
+		key = k.Value
+	}
+	if s, ok := key.(*ast.StringNode); ok {
+		return s.Value
+	}
+	return key.GetToken().Value
+}
+
+func (p *printer) mapping(n *ast.MappingNode, k kind, indent int) {
+	spaced := rules[k].spaced
+
+	for i, e := range p.entries(n, k) {
+		if i != 0 && spaced {
+			p.buf.WriteByte('\n')
+		}
+
+		if e.GetComment() != nil {
+			for _, comment := range e.GetComment().Comments[p.written[e]:] {
+				p.line(indent, strings.TrimSpace(comment.String()))
+			}
+		}
+
+		key := p.key(e, k)
+		child := k.child(keyOf(e))
+
+		if text, ok := p.inline(e.Value, child, indent); ok {
+			p.line(indent, key+":"+prefixed(text)+lineComment(e.Key))
+		} else {
+			prefix, value := properties(e.Value)
+			p.line(indent, key+":"+prefixed(prefix)+lineComment(e.Key))
+			p.block(value, child, indent+indentWidth)
+		}
+
+		p.comments(e.FootComment, indent)
+	}
+}
+
+func (p *printer) sequence(n *ast.SequenceNode, k kind, indent int) {
+	for i, item := range n.Values {
+		if i == 0 {
+			p.comments(n.GetComment(), indent)
+		}
+		if i < len(n.ValueHeadComments) {
+			p.comments(n.ValueHeadComments[i], indent)
+		}
+
+		if text, ok := p.inline(item, k, indent); ok {
+			p.line(indent, "-"+prefixed(text))
+			continue
+		}
+
+		prefix, value := properties(item)
+
+		// properties and comments of collections need own line
+		if prefix != "" || lineComment(item) != "" {
+			p.line(indent, "-"+prefixed(prefix)+lineComment(item))
+			p.block(value, k, indent+indentWidth)
+			continue
+		}
+
+		// the first line of nested block follows the dash
+		var nested printer
+		nested.opts = p.opts
+		nested.block(value, k, indent+indentWidth)
+
+		lines := strings.SplitAfter(nested.buf.String(), "\n")
+		for i, line := range lines {
+			trimmed := strings.TrimLeft(line, " ")
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			lines[i] = strings.Repeat(" ", indent) + "- " + trimmed
+			break
+		}
+		p.buf.WriteString(strings.Join(lines, ""))
+	}
+
+	p.comments(n.FootComment, indent)
+}
+
+// properties splits anchor or tag of node from the node it's declared on
+func properties(n ast.Node) (string, ast.Node) {
+	switch n := n.(type) {
+	case *ast.AnchorNode:
+		prefix, value := properties(n.Value)
+		return strings.TrimSpace("&" + n.Name.GetToken().Value + " " + prefix), value
+	case *ast.TagNode:
+		prefix, value := properties(n.Value)
+		return strings.TrimSpace(n.Start.Value + " " + prefix), value
+	default:
+		return "", n
+	}
+}
+
+func prefixed(text string) string {
+	if text == "" {
+		return ""
+	}
+	return " " + text
+}
+
+// key writes key of entry in mapping of kind k
+func (p *printer) key(e *ast.MappingValueNode, k kind) string {
+	var key ast.Node = e.Key
+	if mapping, ok := key.(*ast.MappingKeyNode); ok {
+		key = mapping.Value
+	}
+
+	switch key := key.(type) {
+	case *ast.StringNode:
+		value := key.Value
+		if k == responsesKind && isStatusCode(value) {
+			return value
+		}
+		// keys of object definitions are property names with ? suffix
+		if k == schemaKind && !docs.IsExtension(value) {
+			value = normalizeExpression(value)
+		}
+		return quote(value, false)
+	case *ast.MergeKeyNode:
+		return "<<"
+	default:
+		text, _ := p.inline(key, anyKind, 0)
+		return text
+	}
+}
+
+// inline returns text of node which fits single line, with line comment of
+// the node. Literal blocks continue on lines indented more than indent.
+func (p *printer) inline(n ast.Node, k kind, indent int) (string, bool) {
+	switch n := n.(type) {
+	case *ast.AnchorNode, *ast.TagNode:
+		prefix, value := properties(n)
+		text, ok := p.inline(value, k, indent)
+		return prefix + prefixed(text), ok
+	case *ast.AliasNode:
+		return "*" + n.Value.GetToken().Value + lineComment(n), true
+	case *ast.StringNode:
+		value := n.Value
+		if k == schemaKind {
+			value = normalizeExpression(value)
+		}
+		if strings.Contains(value, "\n") {
+			if literal, ok := literalBlock(value, indent+indentWidth); ok {
+				header, body, _ := strings.Cut(literal, "\n")
+				return header + lineComment(n) + "\n" + body, true
+			}
+		}
+		return quote(value, false) + lineComment(n), true
+	case *ast.LiteralNode:
+		value := n.Value.Value
+		if literal, ok := literalBlock(value, indent+indentWidth); ok {
+			if comment := lineComment(n); comment != "" {
+				header, body, _ := strings.Cut(literal, "\n")
+				return header + comment + "\n" + body, true
+			}
+			return literal, true
+		}
+		return quote(value, false) + lineComment(n), true
+	case *ast.NullNode:
+		text := ""
+		if token := n.GetToken(); token != nil && token.Value != "" {
+			text = "null"
+		}
+		return text + lineComment(n), true
+	case *ast.MappingNode:
+		if len(n.Values) == 0 {
+			return "{}" + lineComment(n), true
+		}
+		if text, ok := p.flowMapping(n, k); ok {
+			return text + lineComment(n), true
+		}
+		return "", false
+	case *ast.SequenceNode:
+		if len(n.Values) == 0 {
+			return "[]" + lineComment(n), true
+		}
+		if text, ok := p.flowSequence(n, k); ok {
+			return text + lineComment(n), true
+		}
+		return "", false
+	case *ast.MappingValueNode:
+		// single entry mapping, e.g. item of sequence
+		return "", false
+	default:
+		token := n.GetToken()
+		if token == nil {
+			return "", true
+		}
+		return token.Value + lineComment(n), true
+	}
+}
+
+// flowScalar returns text of scalar inside flow collection
+func (p *printer) flowScalar(n ast.Node, k kind) (string, bool) {
+	if n.GetComment() != nil {
+		return "", false
+	}
+
+	switch n := n.(type) {
+	case *ast.StringNode:
+		value := n.Value
+		if k == schemaKind {
+			value = normalizeExpression(value)
+		}
+		return quote(value, true), true
+	case *ast.MappingNode, *ast.SequenceNode, *ast.MappingValueNode, *ast.LiteralNode,
+		*ast.AnchorNode, *ast.AliasNode, *ast.TagNode:
+		return "", false
+	default:
+		text, ok := p.inline(n, k, 0)
+		return text, ok && !strings.Contains(text, "\n")
+	}
+}
+
+// flowSequence keeps flow style of sequences of scalars, e.g. [a, b]
+func (p *printer) flowSequence(n *ast.SequenceNode, k kind) (string, bool) {
+	if !n.IsFlowStyle {
+		return "", false
+	}
+
+	var items []string
+	for _, item := range n.Values {
+		text, ok := p.flowScalar(item, k)
+		if !ok {
+			return "", false
+		}
+		items = append(items, text)
+	}
+
+	return "[" + strings.Join(items, ", ") + "]", true
+}
+
+// flowMapping keeps flow style of mappings of scalars, e.g. {a: 1}
+func (p *printer) flowMapping(n *ast.MappingNode, k kind) (string, bool) {
+	if !n.IsFlowStyle {
+		return "", false
+	}
+
+	var entries []string
+	for _, e := range p.entries(n, k) {
+		if e.GetComment() != nil || e.Key.GetComment() != nil {
+			return "", false
+		}
+
+		key, ok := p.flowScalar(e.Key, anyKind)
+		if !ok {
+			return "", false
+		}
+		if k == schemaKind && !docs.IsExtension(keyOf(e)) {
+			key = quote(normalizeExpression(keyOf(e)), true)
+		}
+		if k == responsesKind && isStatusCode(keyOf(e)) {
+			key = keyOf(e)
+		}
+
+		value, ok := p.flowScalar(e.Value, k.child(keyOf(e)))
+		if !ok {
+			return "", false
+		}
+
+		entries = append(entries, key+":"+prefixed(value))
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}", true
+}
+
+// isStatusCode reports whether key of responses is status code, they are
+// written unquoted however source quotes them, e.g. 404
+func isStatusCode(key string) bool {
+	return len(key) == 3 && strings.Trim(key, "0123456789") == ""
+}
+
+// quote returns plain scalar when it reads back as the same string, double
+// quoted one otherwise
+func quote(s string, flow bool) string {
+	out, err := yaml.Marshal(s)
+	if err != nil {
+		return strconv.Quote(s)
+	}
+
+	text := strings.TrimSuffix(string(out), "\n")
+
+	if strings.ContainsAny(text, "\n") {
+		return strconv.Quote(s)
+	}
+
+	if flow && !strings.HasPrefix(text, `"`) && strings.ContainsAny(text, ",[]{}") {
+		return strconv.Quote(s)
+	}
+
+	return text
+}
+
+// literalBlock writes multi-line string as literal block scalar, lines are
+// indented by indent. Strings literal blocks can't hold are refused.
+func literalBlock(s string, indent int) (string, bool) {
+	if strings.ContainsAny(s, "\r\t") || strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\n") {
+		return "", false
+	}
+
+	body := strings.TrimRight(s, "\n")
+	trailing := len(s) - len(body)
+
+	header := "|"
+	switch {
+	case trailing == 0:
+		header = "|-"
+	case trailing > 1:
+		header = "|+"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(header)
+
+	for _, line := range strings.Split(body, "\n") {
+		sb.WriteByte('\n')
+		if line != "" {
+			sb.WriteString(strings.Repeat(" ", indent) + line)
+		}
+	}
+
+	// kept trailing line breaks are empty lines
+	if header == "|+" {
+		sb.WriteString(strings.Repeat("\n", trailing-1))
+	}
+
+	return sb.String(), true
+}
+
+// normalizeExpression removes whitespace of schema expression, except
+// whitespace of quoted default values
+func normalizeExpression(s string) string {
+	var sb strings.Builder
+	quoted := false
+
+	for _, r := range s {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if !quoted && unicode.IsSpace(r) {
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,102 @@
+package formatting_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/formatting"
+)
+
+// messy is valid qapi document written out of canonical layout
+const messy = `# Users API
+paths:
+  /users:
+    get:   # lists users
+      responses:
+        200:
+          application/json: "<User> []"
+          description: 'ok'
+        "404": {description: not found}
+    post:
+      id: create_user
+      body:
+        application/json:   < NewUser >
+      responses:
+        '201': {description: created}
+        "409":
+          description: conflict
+schemas:
+  User: {id: integer, name: "string( 1 : 64 )"}
+  NewUser:
+    # shown in forms
+    name: string(1:64)
+servers:
+  - description: local
+    url: /
+info:
+  version: "1"
+  title: Users
+`
+
+func TestFormatIdempotent(t *testing.T) {
+	inputs := map[string]string{"messy": messy}
+
+	files, err := filepath.Glob(filepath.Join("..", "compilation", "testdata", "*.qapi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs[filepath.Base(file)] = string(data)
+	}
+
+	for name, input := range inputs {
+		for _, order := range []compilation.Order{compilation.OrderSorted, compilation.OrderSource} {
+			first, err := formatting.Format([]byte(input), formatting.Options{Order: order})
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+
+			second, err := formatting.Format(first, formatting.Options{Order: order})
+			if err != nil {
+				t.Fatalf("%v: formatting formatted document: %v", name, err)
+			}
+
+			if string(first) != string(second) {
+				t.Errorf("%v, order %v: formatting is not idempotent, first\n%s\nsecond\n%s", name, order, first, second)
+			}
+		}
+	}
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	out, err := formatting.Format([]byte(messy), formatting.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, comment := range []string{"# Users API", "# lists users", "# shown in forms"} {
+		if !strings.Contains(string(out), comment) {
+			t.Errorf("comment %q is lost\n%s", comment, out)
+		}
+	}
+}
+
+func TestFormatStatusCodes(t *testing.T) {
+	out, err := formatting.Format([]byte(messy), formatting.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []string{"200:", "201:", "404:", "409:"} {
+		if !strings.Contains(string(out), " "+code) {
+			t.Errorf("status code %v is not written unquoted\n%s", code, out)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package formatting
+
+import "github.com/masnyjimmy/qapi/docs"
+
+// kind is role of YAML node in qapi document, it selects how node is
+// formatted
+type kind int
+
+const (
+	// anyKind nodes keep order of keys, e.g. extensions
+	anyKind kind = iota
+	rootKind
+	infoKind
+	serverKind
+	tagKind
+	schemasKind
+	// schemaKind nodes are schema expressions or object definitions
+	schemaKind
+	securitySchemesKind
+	securitySchemeKind
+	traitsKind
+	traitKind
+	paramKind
+	typedKind
+	responseGroupsKind
+	responsesKind
+	responseKind
+	linksKind
+	linkKind
+	policyKind
+	pathsKind
+	pathKind
+	pathTraitsKind
+	webhooksKind
+	pathItemKind
+	methodKind
+	callbacksKind
+	callbackKind
+)
+
+// rule describes mapping of kind. Keys of order come first in its order,
+// "x-" stands for extensions and "*" for the other keys, which keep order
+// of source.
+type rule struct {
+	order []string
+	// kinds of values of keys, items of sequences get kind of the sequence
+	fields map[string]kind
+	// kind of values of keys missing in fields
+	rest kind
+	// entries are separated by blank lines
+	spaced bool
+	// entries are sorted by key with OrderSorted
+	sortable bool
+}
+
+var methodOrder = []string{"mount", "tags", "traits", "defaultResponses", "get", "post", "put", "patch", "delete", "x-", "*"}
+
+var pathFields = map[string]kind{
+	"mount":            anyKind,
+	"tags":             anyKind,
+	"traits":           pathTraitsKind,
+	"defaultResponses": policyKind,
+	"get":              methodKind,
+	"post":             methodKind,
+	"put":              methodKind,
+	"patch":            methodKind,
+	"delete":           methodKind,
+}
+
+var rules = map[kind]rule{
+	rootKind: {
+		order: []string{"imports", "info", "servers", "tags", "externals", "schemas", "securitySchemes",
+			"traits", "defaultResponses", "defaultResponseGroups", "paths", "webhooks", "x-", "*"},
+		fields: map[string]kind{
+			"info":                  infoKind,
+			"servers":               serverKind,
+			"tags":                  tagKind,
+			"schemas":               schemasKind,
+			"securitySchemes":       securitySchemesKind,
+			"traits":                traitsKind,
+			"defaultResponses":      responsesKind,
+			"defaultResponseGroups": responseGroupsKind,
+			"paths":                 pathsKind,
+			"webhooks":              webhooksKind,
+		},
+		spaced: true,
+	},
+	infoKind:   {order: []string{"title", "version", "description", "x-", "*"}},
+	serverKind: {order: []string{"url", "description", "x-", "*"}},
+	tagKind:    {order: []string{"name", "description", "x-", "*"}},
+	schemasKind: {
+		rest:     schemaKind,
+		spaced:   true,
+		sortable: true,
+	},
+	securitySchemesKind: {rest: securitySchemeKind, spaced: true},
+	securitySchemeKind: {
+		order: []string{"type", "description", "name", "in", "scheme", "bearerFormat", "flows", "openIdConnectUrl", "x-", "*"},
+	},
+	traitsKind: {rest: traitKind, spaced: true},
+	traitKind: {
+		order: []string{"traits", "params", "headers", "responseHeaders", "body", "responses", "tags", "security", "x-", "*"},
+		fields: map[string]kind{
+			"params":          paramKind,
+			"headers":         paramKind,
+			"responseHeaders": paramKind,
+			"body":            typedKind,
+			"responses":       responsesKind,
+		},
+	},
+	paramKind: {
+		order:  []string{"name", "schema", "required", "x-", "*"},
+		fields: map[string]kind{"schema": schemaKind},
+	},
+	typedKind:          {rest: schemaKind},
+	responseGroupsKind: {rest: responsesKind, spaced: true},
+	responsesKind:      {rest: responseKind},
+	responseKind: {
+		order: []string{"description", "headers", "links", "*", "x-"},
+		fields: map[string]kind{
+			"description": anyKind,
+			"headers":     paramKind,
+			"links":       linksKind,
+		},
+		rest: schemaKind,
+	},
+	linksKind:  {rest: linkKind},
+	linkKind:   {order: []string{"operation", "params", "body", "description", "*"}},
+	policyKind: {order: []string{"groups", "include", "exclude", "*"}},
+	pathsKind:  {rest: pathKind, spaced: true},
+	pathKind: {
+		order:  methodOrder,
+		fields: pathFields,
+		rest:   pathKind,
+	},
+	pathTraitsKind: {order: []string{"all", "get", "post", "put", "patch", "delete", "*"}},
+	webhooksKind:   {rest: pathItemKind, spaced: true},
+	pathItemKind: {
+		order:  methodOrder,
+		fields: pathFields,
+	},
+	methodKind: {
+		order: []string{"id", "description", "deprecated", "traits", "excludeTraits", "defaultResponses", "params", "headers",
+			"body", "responses", "security", "callbacks", "x-", "*"},
+		fields: map[string]kind{
+			"defaultResponses": policyKind,
+			"params":           paramKind,
+			"headers":          paramKind,
+			"body":             typedKind,
+			"responses":        responsesKind,
+			"callbacks":        callbacksKind,
+		},
+	},
+	callbacksKind: {rest: callbackKind},
+	callbackKind:  {rest: pathItemKind},
+}
+
+// child returns kind of value of key in mapping of kind k
+func (k kind) child(key string) kind {
+	if docs.IsExtension(key) {
+		return anyKind
+	}
+
+	// properties of object definitions
+	if k == schemaKind {
+		return schemaKind
+	}
+
+	r, has := rules[k]
+	if !has {
+		return anyKind
+	}
+
+	if child, has := r.fields[key]; has {
+		return child
+	}
+
+	return r.rest
+}
@@ -0,0 +1,70 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// Config of lint run, read from YAML file, e.g.
+//
+//	rules:
+//	  operation-id-snake-case: error
+//	  plural-collections: off
+type Config struct {
+	// severity by rule name: error, warning, info or off, rules missing
+	// keep their default severity
+	Rules map[string]string `yaml:"rules"`
+}
+
+// LoadConfig reads config file
+func LoadConfig(file string) (Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	if err := yaml.UnmarshalWithOptions(data, &config, yaml.DisallowUnknownField()); err != nil {
+		return Config{}, fmt.Errorf("invalid lint config %v: %w", file, err)
+	}
+
+	if _, err := config.severities(); err != nil {
+		return Config{}, fmt.Errorf("invalid lint config %v: %w", file, err)
+	}
+
+	return config, nil
+}
+
+type ruleSeverity struct {
+	severity docs.Severity
+	enabled  bool
+}
+
+// severities parses configured severities, rules must be registered
+func (c Config) severities() (map[string]ruleSeverity, error) {
+	out := make(map[string]ruleSeverity, len(c.Rules))
+
+	for name, value := range c.Rules {
+		if _, has := rules[name]; !has {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+
+		switch value {
+		case "error":
+			out[name] = ruleSeverity{docs.SeverityError, true}
+		case "warning":
+			out[name] = ruleSeverity{docs.SeverityWarning, true}
+		case "info":
+			out[name] = ruleSeverity{docs.SeverityInfo, true}
+		case "off":
+			out[name] = ruleSeverity{}
+		default:
+			return nil, fmt.Errorf("unknown severity %q of lint rule %v, expected error, warning, info or off", value, name)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// disableComment matches `# qapi-lint-disable` optionally followed by rule
+// names separated by commas or spaces
+var disableComment = regexp.MustCompile(`(^|\s)#\s*qapi-lint-disable(\s+([\w\-, ]+))?\s*$`)
+
+var ruleName = regexp.MustCompile(`[\w\-]+`)
+
+// directive is disable comment found in source file
+type directive struct {
+	// rules disabled by the comment, all when empty
+	rules []string
+	// comment is the only thing on its line
+	standalone bool
+}
+
+func (d directive) disables(rule string) bool {
+	return len(d.rules) == 0 || slices.Contains(d.rules, rule)
+}
+
+// disabled finds disable comments of files diagnostics are located in
+type disabled struct {
+	source *docs.Source
+	// directives of files by line
+	files map[string]map[int]directive
+	// warnings about unknown rules of directives read so far
+	unknown docs.Diagnostics
+}
+
+func newDisabled(source *docs.Source) *disabled {
+	return &disabled{
+		source: source,
+		files:  make(map[string]map[int]directive),
+	}
+}
+
+// directives returns disable comments of file, unreadable files have none
+func (d *disabled) directives(file string) map[int]directive {
+	if found, has := d.files[file]; has {
+		return found
+	}
+
+	out := make(map[int]directive)
+	d.files[file] = out
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return out
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		match := disableComment.FindStringSubmatchIndex(text)
+		if match == nil {
+			continue
+		}
+
+		var dir directive
+		if match[6] >= 0 {
+			for _, loc := range ruleName.FindAllStringIndex(text[match[6]:match[7]], -1) {
+				start, end := match[6]+loc[0], match[6]+loc[1]
+				name := text[start:end]
+
+				if _, has := rules[name]; !has {
+					d.unknown = append(d.unknown, docs.Diagnostic{
+						Severity: docs.SeverityWarning,
+						Pos:      docs.Position{File: file, Line: line, Column: start + 1},
+						Message:  fmt.Sprintf("unknown lint rule %q in disable comment", name),
+					})
+				}
+
+				dir.rules = append(dir.rules, name)
+			}
+		}
+		dir.standalone = strings.TrimSpace(text[:match[0]]) == ""
+		out[line] = dir
+	}
+
+	return out
+}
+
+// suppressed reports whether finding is disabled by comment at the line of
+// its node or any node above it, or standalone comment on the line before
+func (d *disabled) suppressed(f finding) bool {
+	for n := len(f.Path); n > 0; n-- {
+		pos := f.Pos
+		if n != len(f.Path) {
+			pos = d.source.Position(f.Path[:n])
+		}
+		if !pos.IsValid() {
+			continue
+		}
+
+		directives := d.directives(pos.File)
+
+		if dir, has := directives[pos.Line]; has && dir.disables(f.rule) {
+			return true
+		}
+		if dir, has := directives[pos.Line-1]; has && dir.standalone && dir.disables(f.rule) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *disabled) filter(findings []finding) []finding {
+	return slices.DeleteFunc(findings, d.suppressed)
+}
+
+// unknownRules returns warnings about unknown rules disabled by comments of
+// all files of the source
+func (d *disabled) unknownRules() docs.Diagnostics {
+	for _, file := range d.source.Files() {
+		d.directives(file)
+	}
+	return d.unknown
+}
@@ -0,0 +1,118 @@
+package lint_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/masnyjimmy/qapi/lint"
+	"github.com/masnyjimmy/qapi/loader"
+)
+
+// lintSource lints qapi document written to file, disable comments are
+// read from it. Diagnostics are returned as line:column message.
+func lintSource(t *testing.T, source string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "api.qapi.yaml")
+	if err := os.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := loader.Load(file)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	diagnostics, err := lint.Lint(project.Document, lint.Options{Source: project.Source})
+	if err != nil {
+		t.Fatalf("lint: %v\n%v", err, diagnostics)
+	}
+
+	var out string
+	for _, d := range diagnostics {
+		out += fmt.Sprintf("%d:%d %v\n", d.Pos.Line, d.Pos.Column, d.Message)
+	}
+	return out
+}
+
+const (
+	infoDescription = "1:1 info has no description (descriptions)\n"
+	getDescription  = "8:5 get /user has no description (descriptions)\n"
+	getTags         = "8:5 get /user has no tags (operation-tags)\n"
+	idCase          = "9:7 operation id getUser is not snake_case (operation-id-snake-case)\n"
+)
+
+func TestDisableComments(t *testing.T) {
+	tests := []struct {
+		name string
+		// comments of info, get and id lines
+		info, get, id string
+		// standalone comment on the line above id
+		above string
+		want  string
+	}{
+		{
+			name: "none",
+			want: infoDescription + getDescription + getTags + idCase,
+		},
+		{
+			name: "all rules of node and nodes under it",
+			get:  " # qapi-lint-disable",
+			want: infoDescription,
+		},
+		{
+			name: "rules separated by commas",
+			get:  " # qapi-lint-disable descriptions, operation-tags",
+			want: infoDescription + idCase,
+		},
+		{
+			name: "rules separated by spaces",
+			get:  " # qapi-lint-disable descriptions operation-tags",
+			want: infoDescription + idCase,
+		},
+		{
+			name:  "standalone comment disables the next line",
+			above: "      # qapi-lint-disable operation-id-snake-case\n",
+			want:  infoDescription + getDescription + getTags,
+		},
+		{
+			name: "comment of other rule",
+			id:   " # qapi-lint-disable operation-tags",
+			want: infoDescription + getDescription + getTags + idCase,
+		},
+		{
+			name: "unknown rule",
+			info: " # qapi-lint-disable descriptions,bogus",
+			want: "1:40 unknown lint rule \"bogus\" in disable comment\n" +
+				getDescription + getTags + idCase,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := "info:" + test.info + `
+  title: Lint
+  version: "1"
+servers:
+  - url: /
+paths:
+  /user:
+    get:` + test.get + "\n" + test.above + `      id: getUser` + test.id + `
+      responses:
+        200:
+          description: ok
+`
+			if test.above != "" {
+				// the standalone comment shifts the id line
+				test.want = strings.Replace(test.want, "9:7 ", "10:7 ", 1)
+			}
+
+			if got := lintSource(t, source); got != test.want {
+				t.Errorf("got diagnostics\n%v\nwant\n%v", got, test.want)
+			}
+		})
+	}
+}
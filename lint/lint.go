@@ -0,0 +1,254 @@
+package lint
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// Rule checks single style convention of the document
+type Rule struct {
+	// kebab-case name used in config and disable comments
+	Name        string
+	Description string
+	// severity used when config doesn't set one
+	Severity docs.Severity
+	Check    func(c *Context)
+}
+
+var rules = make(map[string]Rule)
+
+// Register makes rule available to every lint run, it panics when rule
+// without name or check, or rule with taken name is registered
+func Register(rule Rule) {
+	if rule.Name == "" || rule.Check == nil {
+		panic("lint: rule requires name and check")
+	}
+	if _, has := rules[rule.Name]; has {
+		panic(fmt.Sprintf("lint: rule %v registered twice", rule.Name))
+	}
+	rules[rule.Name] = rule
+}
+
+// Rules returns registered rules ordered by name
+func Rules() []Rule {
+	out := make([]Rule, 0, len(rules))
+	for _, name := range slices.Sorted(maps.Keys(rules)) {
+		out = append(out, rules[name])
+	}
+	return out
+}
+
+// Operation is method of path or webhook with its compiled operation
+type Operation struct {
+	// compiled path, e.g. /users/{id}, or name of webhook
+	Path    string
+	Method  string
+	Webhook bool
+	// node of qapi method
+	At       docs.NodePath
+	Source   *docs.Method
+	Compiled *compilation.Operation
+}
+
+// PathNode is node of paths section, Path is the full path it declares
+type PathNode struct {
+	Path string
+	At   docs.NodePath
+	Node docs.Path
+}
+
+// Context is passed to checks of rules, problems are reported by Report
+type Context struct {
+	Document *docs.Document
+	Compiled *compilation.Document
+
+	nodes      []PathNode
+	operations []Operation
+
+	rule     Rule
+	severity docs.Severity
+	findings []finding
+}
+
+// finding is diagnostic reported by rule
+type finding struct {
+	rule string
+	docs.Diagnostic
+}
+
+// Report records problem of the current rule located at node, name of the
+// rule is appended to the message
+func (c *Context) Report(at docs.NodePath, format string, args ...any) {
+	c.findings = append(c.findings, finding{
+		rule: c.rule.Name,
+		Diagnostic: docs.Diagnostic{
+			Severity: c.severity,
+			Path:     slices.Clone(at),
+			Message:  fmt.Sprintf(format, args...) + " (" + c.rule.Name + ")",
+		},
+	})
+}
+
+// PathNodes returns nodes of paths section in order of their locations
+func (c *Context) PathNodes() []PathNode {
+	return c.nodes
+}
+
+// Operations returns operations of paths and webhooks in order of their
+// locations
+func (c *Context) Operations() []Operation {
+	return c.operations
+}
+
+var methods = []string{"get", "post", "put", "patch", "delete"}
+
+func methodOf(p *docs.Path, name string) *docs.Method {
+	switch name {
+	case "get":
+		return p.Get
+	case "post":
+		return p.Post
+	case "put":
+		return p.Put
+	case "patch":
+		return p.Patch
+	default:
+		return p.Delete
+	}
+}
+
+func operationOf(p compilation.Path, name string) *compilation.Operation {
+	switch name {
+	case "get":
+		return p.Get
+	case "post":
+		return p.Post
+	case "put":
+		return p.Put
+	case "patch":
+		return p.Patch
+	default:
+		return p.Delete
+	}
+}
+
+// collect walks paths the way compiler does, pairing qapi methods with
+// their compiled operations
+func (c *Context) collect() {
+	addOperations := func(item docs.Path, key string, at docs.NodePath, compiled compilation.Paths, webhook bool) {
+		for _, method := range methods {
+			source := methodOf(&item, method)
+			if source == nil {
+				continue
+			}
+
+			op := Operation{
+				Path:    key,
+				Method:  method,
+				Webhook: webhook,
+				At:      slices.Concat(at, docs.NodePath{method}),
+				Source:  source,
+			}
+			if p, has := compiled[key]; has {
+				op.Compiled = operationOf(p, method)
+			}
+			if op.Compiled != nil {
+				c.operations = append(c.operations, op)
+			}
+		}
+	}
+
+	var walk func(current string, at docs.NodePath, node docs.Path)
+	walk = func(current string, at docs.NodePath, node docs.Path) {
+		c.nodes = append(c.nodes, PathNode{Path: current, At: at, Node: node})
+		addOperations(node, current, at, c.Compiled.Paths, false)
+
+		for _, key := range slices.Sorted(maps.Keys(node.Nested)) {
+			walk(path.Join(current, key), slices.Concat(at, docs.NodePath{key}), node.Nested[key])
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(c.Document.Paths)) {
+		walk(key, docs.NodePath{"paths", key}, c.Document.Paths[key])
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Document.Webhooks)) {
+		addOperations(c.Document.Webhooks[name], name, docs.NodePath{"webhooks", name}, c.Compiled.Webhooks, true)
+	}
+}
+
+// Options of lint run, zero value runs rules with their default severities
+type Options struct {
+	Config Config
+	// source of the document, used to locate diagnostics and find disable
+	// comments
+	Source *docs.Source
+	// directory external files are relative to
+	BaseDir string
+}
+
+// Lint compiles document and checks it with registered rules. Failed
+// compilation is returned as error with its diagnostics, otherwise
+// warnings of compilation, e.g. unused schemas, and diagnostics of rules are
+// returned located and sorted.
+func Lint(document *docs.Document, opts Options) (docs.Diagnostics, error) {
+	severities, err := opts.Config.severities()
+	if err != nil {
+		return nil, err
+	}
+
+	var compiled compilation.Document
+	warnings, err := compilation.Compile(&compiled, document, compilation.Options{
+		Source:  opts.Source,
+		BaseDir: opts.BaseDir,
+	})
+	if err != nil {
+		return warnings, err
+	}
+
+	c := &Context{
+		Document: document,
+		Compiled: &compiled,
+	}
+	c.collect()
+
+	for _, rule := range Rules() {
+		severity, enabled := rule.Severity, true
+		if configured, has := severities[rule.Name]; has {
+			severity, enabled = configured.severity, configured.enabled
+		}
+		if !enabled {
+			continue
+		}
+
+		c.rule, c.severity = rule, severity
+		rule.Check(c)
+	}
+
+	diagnostics := warnings
+
+	findings := c.findings
+	if opts.Source != nil {
+		opts.Source.LocateAll(diagnostics)
+
+		for i := range findings {
+			findings[i].Pos = opts.Source.Position(findings[i].Path)
+		}
+
+		disabled := newDisabled(opts.Source)
+		findings = disabled.filter(findings)
+		diagnostics = append(diagnostics, disabled.unknownRules()...)
+	}
+
+	for _, f := range findings {
+		diagnostics = append(diagnostics, f.Diagnostic)
+	}
+	diagnostics.Sort()
+
+	return diagnostics, nil
+}
@@ -0,0 +1,31 @@
+package lint_test
+
+import "testing"
+
+func TestCompileWarnings(t *testing.T) {
+	source := `info:
+  title: Lint
+  description: compile warnings
+  version: "1"
+servers:
+  - url: /
+schemas:
+  Legacy:
+    id: integer
+paths:
+  /users:
+    tags: [users]
+    get:
+      id: list_users
+      description: users
+      responses:
+        200:
+          description: ok
+`
+	// reported once, by the compiler
+	want := "8:3 schema Legacy is not used by any operation, webhook or default response\n"
+
+	if got := lintSource(t, source); got != want {
+		t.Errorf("got diagnostics\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package lint
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/compilation"
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// built-in rules
+func init() {
+	Register(Rule{
+		Name:        "operation-id-snake-case",
+		Description: "operation ids are snake_case, e.g. list_users",
+		Severity:    docs.SeverityWarning,
+		Check:       checkOperationIdCase,
+	})
+	Register(Rule{
+		Name:        "operation-tags",
+		Description: "every operation has a tag",
+		Severity:    docs.SeverityWarning,
+		Check:       checkOperationTags,
+	})
+	Register(Rule{
+		Name:        "error-responses",
+		Description: "every operation of paths except get has a 4XX response",
+		Severity:    docs.SeverityWarning,
+		Check:       checkErrorResponses,
+	})
+	Register(Rule{
+		Name:        "plural-collections",
+		Description: "path segments followed by a path param are plural, e.g. /users/{id}",
+		Severity:    docs.SeverityWarning,
+		Check:       checkPluralCollections,
+	})
+	Register(Rule{
+		Name:        "descriptions",
+		Description: "info, tags and operations have descriptions",
+		Severity:    docs.SeverityInfo,
+		Check:       checkDescriptions,
+	})
+	Register(Rule{
+		Name:        "path-param-number",
+		Description: "path params aren't typed as number, integer or string is used",
+		Severity:    docs.SeverityError,
+		Check:       checkPathParamNumber,
+	})
+}
+
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+func checkOperationIdCase(c *Context) {
+	for _, op := range c.Operations() {
+		if op.Source.Id != "" && !snakeCase.MatchString(op.Source.Id) {
+			c.Report(slices.Concat(op.At, docs.NodePath{"id"}), "operation id %v is not snake_case", op.Source.Id)
+		}
+	}
+}
+
+func checkOperationTags(c *Context) {
+	for _, op := range c.Operations() {
+		if len(op.Compiled.Tags) == 0 {
+			c.Report(op.At, "%v %v has no tags", op.Method, op.Path)
+		}
+	}
+}
+
+func checkErrorResponses(c *Context) {
+	for _, op := range c.Operations() {
+		if op.Webhook || op.Method == "get" {
+			continue
+		}
+
+		if !slices.ContainsFunc(slices.Collect(maps.Keys(op.Compiled.Responses)), func(code string) bool {
+			return strings.HasPrefix(code, "4")
+		}) {
+			c.Report(slices.Concat(op.At, docs.NodePath{"responses"}), "%v %v has no 4XX response", op.Method, op.Path)
+		}
+	}
+}
+
+// irregular plurals and words without plural form
+var pluralWords = map[string]bool{
+	"children": true, "people": true, "men": true, "women": true, "feet": true, "teeth": true, "mice": true,
+	"geese": true, "data": true, "metadata": true, "media": true, "criteria": true, "indices": true,
+	"matrices": true, "series": true, "species": true, "news": true, "info": true, "equipment": true,
+}
+
+// isPlural guesses whether last word of segment is plural, e.g. order-items
+func isPlural(segment string) bool {
+	words := strings.FieldsFunc(strings.ToLower(segment), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+	if len(words) == 0 {
+		return true
+	}
+
+	word := words[len(words)-1]
+	if pluralWords[word] {
+		return true
+	}
+	return strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss")
+}
+
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func checkPluralCollections(c *Context) {
+	checked := make(map[string]bool)
+
+	for _, node := range c.PathNodes() {
+		segments := strings.Split(node.Path, "/")
+		// segments declared by key of the node, nested keys may declare
+		// several
+		own := len(strings.Split(strings.Trim(node.At[len(node.At)-1], "/"), "/"))
+
+		for i := len(segments) - own; i < len(segments); i++ {
+			if i < 1 || !isPathParam(segments[i]) || isPathParam(segments[i-1]) || segments[i-1] == "" {
+				continue
+			}
+
+			collection := strings.Join(segments[:i], "/")
+			if checked[collection] {
+				continue
+			}
+			checked[collection] = true
+
+			if !isPlural(segments[i-1]) {
+				c.Report(node.At, "collection %v is not plural", collection)
+			}
+		}
+	}
+}
+
+func checkDescriptions(c *Context) {
+	if c.Document.Info.Description == "" {
+		c.Report(docs.NodePath{"info"}, "info has no description")
+	}
+
+	for i, tag := range c.Document.Tags {
+		if tag.Description == "" {
+			c.Report(docs.NodePath{"tags", strconv.Itoa(i)}, "tag %v has no description", tag.Name)
+		}
+	}
+
+	for _, op := range c.Operations() {
+		if op.Source.Description == "" {
+			c.Report(op.At, "%v %v has no description", op.Method, op.Path)
+		}
+	}
+}
+
+func checkPathParamNumber(c *Context) {
+	for _, op := range c.Operations() {
+		for _, param := range op.Compiled.Parameters {
+			if param.In != compilation.InPath {
+				continue
+			}
+
+			schema, ok := param.Schema.GetSchema()
+			if !ok || schema.Type != compilation.SchemaNumber {
+				continue
+			}
+
+			at := op.At
+			if idx := slices.IndexFunc(op.Source.Params, func(p docs.Param) bool {
+				return p.Name == param.Name
+			}); idx >= 0 {
+				at = slices.Concat(op.At, docs.NodePath{"params", strconv.Itoa(idx)})
+			}
+
+			c.Report(at, "path param %v of %v %v is number, use integer or string", param.Name, op.Method, op.Path)
+		}
+	}
+}
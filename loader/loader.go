@@ -0,0 +1,260 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/masnyjimmy/qapi/docs"
+	"github.com/masnyjimmy/qapi/validation"
+)
+
+// ErrParse is returned when valid document can't be decoded
+var ErrParse = errors.New("unable to parse document")
+
+// Project is qapi document merged with the files it imports
+type Project struct {
+	Document *docs.Document
+	// locates nodes of Document in files they come from
+	Source *docs.Source
+	// loaded files, the root one first
+	Files []string
+}
+
+type loader struct {
+	files []string
+	// absolute paths of loaded files
+	loaded map[string]bool
+	// absolute paths of files being loaded, to detect cycles
+	loading     map[string]bool
+	diagnostics docs.Diagnostics
+}
+
+// Load reads, validates and parses file with files it imports and mounts.
+// Problems of the documents, imports and mounts are returned as located docs.Diagnostics, failure
+// to read the file as is.
+func Load(file string) (*Project, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &loader{
+		loaded:  make(map[string]bool),
+		loading: make(map[string]bool),
+	}
+
+	document, source, err := l.load(file, data, validation.Validate)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.diagnostics.HasErrors() {
+		l.diagnostics.Sort()
+		return nil, l.diagnostics
+	}
+
+	return &Project{
+		Document: document,
+		Source:   source,
+		Files:    l.files,
+	}, nil
+}
+
+// errorf records error located at path of source
+func (l *loader) errorf(source *docs.Source, path docs.NodePath, format string, args ...any) {
+	l.diagnostics = append(l.diagnostics, docs.Diagnostic{
+		Severity: docs.SeverityError,
+		Path:     path,
+		Pos:      source.Position(path),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// load validates and parses data of file and merges files it mounts and
+// imports into it, nil document is returned when it's invalid
+func (l *loader) load(file string, data []byte, validate func([]byte) error) (*docs.Document, *docs.Source, error) {
+	abs, _ := filepath.Abs(file)
+	l.loaded[abs] = true
+	l.loading[abs] = true
+	defer delete(l.loading, abs)
+
+	l.files = append(l.files, file)
+
+	// used only to locate errors, invalid yaml is reported by validation
+	source, _ := docs.ParseSource(file, data)
+	if source == nil {
+		source = &docs.Source{File: file}
+	}
+
+	if err := validate(data); err != nil {
+		diagnostics := docs.AsDiagnostics(err)
+		source.LocateAll(diagnostics)
+		l.diagnostics = append(l.diagnostics, diagnostics...)
+		return nil, nil, nil
+	}
+
+	var document docs.Document
+
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, fmt.Errorf("%w %v: %w", ErrParse, file, err)
+	}
+
+	if err := l.mounts(&document, file, source, document.Paths, docs.NodePath{"paths"}); err != nil {
+		return nil, nil, err
+	}
+
+	for i, pattern := range document.Imports {
+		at := docs.NodePath{"imports", strconv.Itoa(i)}
+
+		files, err := expand(filepath.Join(filepath.Dir(file), pattern))
+		if err != nil {
+			l.errorf(source, at, "invalid import %v: %v", pattern, err)
+			continue
+		}
+
+		if len(files) == 0 {
+			l.errorf(source, at, "import %v matches no files", pattern)
+			continue
+		}
+
+		for _, imported := range files {
+			// imported by other file before
+			if abs, _ := filepath.Abs(imported); l.loaded[abs] && !l.loading[abs] {
+				continue
+			}
+
+			sub, subSource, err := l.loadFragment(source, at, imported)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if sub != nil {
+				m := merger{loader: l, dst: source, src: subSource}
+				m.document(&document, sub)
+			}
+		}
+	}
+
+	return &document, source, nil
+}
+
+// loadFragment loads file imported or mounted at path of source, problems
+// are reported and nil document is returned. Paths of external files the
+// fragment references are rebased onto directory of source.
+func (l *loader) loadFragment(source *docs.Source, at docs.NodePath, file string) (*docs.Document, *docs.Source, error) {
+	abs, _ := filepath.Abs(file)
+
+	if l.loading[abs] {
+		l.errorf(source, at, "cycle, %v is already being loaded", file)
+		return nil, nil, nil
+	}
+
+	if l.loaded[abs] {
+		l.errorf(source, at, "%v is already loaded", file)
+		return nil, nil, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		l.errorf(source, at, "unable to read file: %v", err)
+		return nil, nil, nil
+	}
+
+	document, fragmentSource, err := l.load(file, data, validation.ValidateFragment)
+	if document != nil {
+		newRebaser(file, source.File).document(document)
+	}
+	return document, fragmentSource, err
+}
+
+// mounts merges files mounted by path nodes of paths, at is path of paths in
+// document of file. Nodes mounted from other files are not visited.
+func (l *loader) mounts(document *docs.Document, file string, source *docs.Source, paths docs.Paths, at docs.NodePath) error {
+	for _, key := range slices.Sorted(maps.Keys(paths)) {
+		node := paths[key]
+		path := append(slices.Clone(at), key)
+
+		if err := l.mounts(document, file, source, node.Nested, path); err != nil {
+			return err
+		}
+
+		if node.Mount == "" {
+			continue
+		}
+
+		mounted := filepath.Join(filepath.Dir(file), node.Mount)
+
+		sub, subSource, err := l.loadFragment(source, append(path, "mount"), mounted)
+		if err != nil {
+			return err
+		}
+
+		if sub == nil {
+			continue
+		}
+
+		// paths of mounted document are nested in the node, the rest is
+		// merged as imported
+		m := merger{
+			loader: l,
+			dst:    source,
+			src:    subSource,
+			from:   docs.NodePath{"paths"},
+			to:     path,
+		}
+
+		node.Nested = m.paths(node.Nested, sub.Paths, docs.NodePath{"paths"})
+		paths[key] = node
+
+		sub.Paths = nil
+		m.document(document, sub)
+	}
+
+	return nil
+}
+
+// expand returns files matched by import, directories are expanded to yaml
+// files they contain
+func expand(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			out = append(out, match)
+			continue
+		}
+
+		entries, err := os.ReadDir(match)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				out = append(out, filepath.Join(match, entry.Name()))
+			}
+		}
+	}
+
+	slices.Sort(out)
+
+	return slices.Compact(out), nil
+}
@@ -0,0 +1,171 @@
+package loader
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// merger merges imported document into the importing one, merged nodes
+// are mounted from source of the imported document
+type merger struct {
+	loader *loader
+	dst    *docs.Source
+	src    *docs.Source
+	// nodes under from in imported document are merged under to, nodes
+	// elsewhere keep their paths
+	from, to docs.NodePath
+}
+
+// target returns path in importing document of node at path of imported one
+func (m *merger) target(path docs.NodePath) docs.NodePath {
+	if len(m.from) == 0 || len(path) < len(m.from) || !slices.Equal(path[:len(m.from)], m.from) {
+		return path
+	}
+	return slices.Concat(m.to, path[len(m.from):])
+}
+
+// conflict reports node at path of imported document, defined already at
+// the same place of the importing one
+func (m *merger) conflict(path docs.NodePath, what string) {
+	m.loader.errorf(m.src, path, "%v is already defined at %v", what, m.dst.Position(m.target(path)))
+}
+
+func (m *merger) mount(path docs.NodePath) {
+	m.dst.Mount(m.target(path), m.src, path)
+}
+
+func (m *merger) document(dst, src *docs.Document) {
+	for i, tag := range src.Tags {
+		idx := slices.IndexFunc(dst.Tags, func(t docs.Tag) bool {
+			return t.Name == tag.Name
+		})
+
+		if idx != -1 {
+			m.loader.errorf(m.src, docs.NodePath{"tags", strconv.Itoa(i)}, "tag %v is already defined at %v",
+				tag.Name, m.dst.Position(docs.NodePath{"tags", strconv.Itoa(idx)}))
+			continue
+		}
+
+		m.dst.Mount(docs.NodePath{"tags", strconv.Itoa(len(dst.Tags))}, m.src, docs.NodePath{"tags", strconv.Itoa(i)})
+		dst.Tags = append(dst.Tags, tag)
+	}
+
+	dst.Schemas = mergeEntries(m, dst.Schemas, src.Schemas, "schema", "schemas")
+	dst.Externals = m.externals(dst.Externals, src.Externals)
+	dst.SecuritySchemes = mergeEntries(m, dst.SecuritySchemes, src.SecuritySchemes, "security scheme", "securitySchemes")
+	dst.Traits = mergeEntries(m, dst.Traits, src.Traits, "trait", "traits")
+	dst.DefaultResponses = mergeEntries(m, dst.DefaultResponses, src.DefaultResponses, "default response", "defaultResponses")
+	dst.DefaultResponseGroups = mergeEntries(m, dst.DefaultResponseGroups, src.DefaultResponseGroups, "default response group", "defaultResponseGroups")
+	dst.Paths = m.paths(dst.Paths, src.Paths, docs.NodePath{"paths"})
+	dst.Webhooks = m.paths(dst.Webhooks, src.Webhooks, docs.NodePath{"webhooks"})
+}
+
+// mergeEntries adds entries of src missing in dst to it, at is path of both
+func mergeEntries[V any](m *merger, dst, src map[string]V, what string, at ...string) map[string]V {
+	for _, name := range slices.Sorted(maps.Keys(src)) {
+		path := append(docs.NodePath{}, at...)
+		path = append(path, name)
+
+		if _, has := dst[name]; has {
+			m.conflict(path, what+" "+name)
+			continue
+		}
+
+		if dst == nil {
+			dst = make(map[string]V)
+		}
+
+		dst[name] = src[name]
+		m.mount(path)
+	}
+
+	return dst
+}
+
+// externals merges aliases of external files, alias defined by both
+// documents is a conflict only when they refer to different files
+func (m *merger) externals(dst, src map[string]string) map[string]string {
+	for alias, file := range src {
+		if defined, has := dst[alias]; has && path.Clean(defined) == path.Clean(file) {
+			delete(src, alias)
+		}
+	}
+
+	return mergeEntries(m, dst, src, "external", "externals")
+}
+
+// paths merges path trees, nodes defined in both are merged as long as they
+// don't define the same fields
+func (m *merger) paths(dst, src docs.Paths, at docs.NodePath) docs.Paths {
+	for _, key := range slices.Sorted(maps.Keys(src)) {
+		path := append(slices.Clone(at), key)
+
+		node, has := dst[key]
+		if !has {
+			if dst == nil {
+				dst = make(docs.Paths)
+			}
+
+			dst[key] = src[key]
+			m.mount(path)
+			continue
+		}
+
+		m.path(&node, src[key], path)
+		dst[key] = node
+	}
+
+	return dst
+}
+
+func (m *merger) path(dst *docs.Path, src docs.Path, at docs.NodePath) {
+	merge := func(name string, dstDefined, srcDefined bool, set func()) {
+		if !srcDefined {
+			return
+		}
+
+		path := append(slices.Clone(at), name)
+
+		if dstDefined {
+			m.conflict(path, fmt.Sprintf("%v of path %v", name, strings.Join(m.target(at)[1:], "")))
+			return
+		}
+
+		set()
+		m.mount(path)
+	}
+
+	merge("tags", len(dst.Tags) != 0, len(src.Tags) != 0, func() { dst.Tags = src.Tags })
+	merge("defaultResponses", dst.DefaultResponses != nil, src.DefaultResponses != nil, func() { dst.DefaultResponses = src.DefaultResponses })
+	merge("mount", dst.Mount != "", src.Mount != "", func() { dst.Mount = src.Mount })
+	merge("traits", !emptyTraits(dst.Traits), !emptyTraits(src.Traits), func() { dst.Traits = src.Traits })
+	merge("get", dst.Get != nil, src.Get != nil, func() { dst.Get = src.Get })
+	merge("post", dst.Post != nil, src.Post != nil, func() { dst.Post = src.Post })
+	merge("put", dst.Put != nil, src.Put != nil, func() { dst.Put = src.Put })
+	merge("patch", dst.Patch != nil, src.Patch != nil, func() { dst.Patch = src.Patch })
+	merge("delete", dst.Delete != nil, src.Delete != nil, func() { dst.Delete = src.Delete })
+
+	for _, key := range slices.Sorted(maps.Keys(src.Extensions)) {
+		_, has := dst.Extensions[key]
+
+		merge(key, has, true, func() {
+			if dst.Extensions == nil {
+				dst.Extensions = make(docs.Extensions)
+			}
+			dst.Extensions[key] = src.Extensions[key]
+		})
+	}
+
+	dst.Nested = m.paths(dst.Nested, src.Nested, at)
+}
+
+func emptyTraits(t docs.PathTraits) bool {
+	return len(t.All) == 0 && len(t.Get) == 0 && len(t.Post) == 0 &&
+		len(t.Put) == 0 && len(t.Patch) == 0 && len(t.Delete) == 0
+}
@@ -0,0 +1,120 @@
+package loader
+
+import (
+	"path/filepath"
+	"regexp"
+
+	"github.com/masnyjimmy/qapi/docs"
+)
+
+// file of reference to external schema, e.g. common.yaml of
+// <common.yaml#Money>
+var fileRefExpr = regexp.MustCompile(`<([\w.\-/]+)#`)
+
+// rebaser rewrites paths of external files, relative to the directory of
+// file declaring them, to be relative to directory of the including file
+type rebaser struct {
+	from, to string
+}
+
+func newRebaser(file, including string) rebaser {
+	from, _ := filepath.Abs(filepath.Dir(file))
+	to, _ := filepath.Abs(filepath.Dir(including))
+	return rebaser{from: from, to: to}
+}
+
+func (r rebaser) path(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+
+	rel, err := filepath.Rel(r.to, filepath.Join(r.from, filepath.FromSlash(file)))
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (r rebaser) schema(schema docs.Schema) docs.Schema {
+	switch value := schema.Value.(type) {
+	case string:
+		schema.Value = fileRefExpr.ReplaceAllStringFunc(value, func(ref string) string {
+			return "<" + r.path(ref[1:len(ref)-1]) + "#"
+		})
+	case docs.Properties:
+		for i := range value {
+			value[i].Schema = r.schema(value[i].Schema)
+		}
+	}
+	return schema
+}
+
+func (r rebaser) params(params docs.Params) {
+	for i := range params {
+		params[i].Schema = r.schema(params[i].Schema)
+	}
+}
+
+func (r rebaser) typed(typed docs.TypedSchema) {
+	for mediaType, schema := range typed {
+		typed[mediaType] = r.schema(schema)
+	}
+}
+
+func (r rebaser) responses(responses docs.Responses) {
+	for _, response := range responses {
+		r.params(response.Headers)
+		r.typed(response.TypedSchema)
+	}
+}
+
+func (r rebaser) method(method *docs.Method) {
+	if method == nil {
+		return
+	}
+
+	r.params(method.Params)
+	r.params(method.Headers)
+	r.typed(method.Body)
+	r.responses(method.Responses)
+
+	for _, callback := range method.Callbacks {
+		r.paths(callback)
+	}
+}
+
+func (r rebaser) paths(paths docs.Paths) {
+	for _, path := range paths {
+		for _, method := range []*docs.Method{path.Get, path.Post, path.Put, path.Patch, path.Delete} {
+			r.method(method)
+		}
+		r.paths(path.Nested)
+	}
+}
+
+// document rebases external aliases and file references of schemas
+func (r rebaser) document(document *docs.Document) {
+	for alias, file := range document.Externals {
+		document.Externals[alias] = r.path(file)
+	}
+
+	for name, schema := range document.Schemas {
+		document.Schemas[name] = r.schema(schema)
+	}
+
+	for _, trait := range document.Traits {
+		r.params(trait.Params)
+		r.params(trait.Headers)
+		r.params(trait.ResponseHeaders)
+		r.typed(trait.Body)
+		r.responses(trait.Responses)
+	}
+
+	r.responses(document.DefaultResponses)
+	for _, group := range document.DefaultResponseGroups {
+		r.responses(group)
+	}
+
+	r.paths(document.Paths)
+	r.paths(document.Webhooks)
+}
@@ -8,17 +8,16 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"path"
+	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/goccy/go-yaml"
 	"github.com/masnyjimmy/qapi/compilation"
 	"github.com/masnyjimmy/qapi/docs"
-	"github.com/masnyjimmy/qapi/validation"
+	"github.com/masnyjimmy/qapi/loader"
 )
 
 //go:embed swagger.html
@@ -64,30 +63,32 @@ func makeUrls(base string) urls {
 	}
 }
 
-func readAPI(filename string) ([]byte, error) {
-	bytes, err := os.ReadFile(filename)
+// readAPI compiles filename, files it consists of are returned to be watched
+func readAPI(filename string) ([]byte, []string, error) {
+	project, err := loader.Load(filename)
 
-	if err != nil {
-		return nil, fmt.Errorf("unable to read file %v: %w", filename, err)
-	}
-
-	if err := validation.Validate(bytes); err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
-	}
+	var diagnostics docs.Diagnostics
 
-	var document docs.Document
-
-	if err := yaml.Unmarshal(bytes, &document); err != nil {
-		return nil, fmt.Errorf("unable to decode document: %w", err)
+	switch {
+	case errors.As(err, &diagnostics):
+		return nil, nil, fmt.Errorf("validation error:\n%w", diagnostics)
+	case errors.Is(err, loader.ErrParse):
+		return nil, nil, fmt.Errorf("unable to decode document: %w", err)
+	case err != nil:
+		return nil, nil, fmt.Errorf("unable to read file %v: %w", filename, err)
 	}
 
-	result, err := compilation.CompileToJSON(&document)
+	result, diagnostics, err := compilation.CompileToJSON(project.Document, compilation.Options{
+		BaseDir: filepath.Dir(filename),
+	})
 
 	if err != nil {
-		return nil, fmt.Errorf("unable to compile document: %w", err)
+		project.Source.LocateAll(diagnostics)
+		diagnostics.Sort()
+		return nil, project.Files, fmt.Errorf("unable to compile document:\n%w", diagnostics.Err())
 	}
 
-	return result, nil
+	return result, project.Files, nil
 }
 
 type Swagger struct {
@@ -117,7 +118,7 @@ func New(document []byte, opt Options) (*Swagger, error) {
 }
 
 func NewFromFile(filename string, opt Options) (*Swagger, error) {
-	result, err := readAPI(filename)
+	result, _, err := readAPI(filename)
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to read api: %w", err)
@@ -130,7 +131,13 @@ var ErrWatcher = errors.New("unable to create watcher")
 
 func NewWithWatcher(filename string, ctx context.Context, opt Options) (*Swagger, error) {
 
-	swagger, err := NewFromFile(filename, opt)
+	result, files, err := readAPI(filename)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read api: %w", err)
+	}
+
+	swagger, err := New(result, opt)
 
 	if err != nil {
 		return nil, err
@@ -142,11 +149,22 @@ func NewWithWatcher(filename string, ctx context.Context, opt Options) (*Swagger
 		return swagger, fmt.Errorf("%w: %w", ErrWatcher, err)
 	}
 
+	swagger.watchImports(watcher, files)
+
 	go swagger.watchHandler(watcher, filename, ctx)
 
 	return swagger, nil
 }
 
+// watchImports watches for updates of files imported by the document
+func (s *Swagger) watchImports(w *Watcher, files []string) {
+	for _, file := range files {
+		if err := w.Add(file); err != nil {
+			s.Logger.Warn("Unable to watch imported file", slog.String("file", file), slog.String("details", err.Error()))
+		}
+	}
+}
+
 func (s *Swagger) watchHandler(w *Watcher, filename string, ctx context.Context) {
 	for {
 		select {
@@ -157,7 +175,8 @@ func (s *Swagger) watchHandler(w *Watcher, filename string, ctx context.Context)
 				return
 			}
 
-			result, err := readAPI(filename)
+			result, files, err := readAPI(filename)
+			s.watchImports(w, files)
 
 			if err != nil {
 				s.Logger.Warn("Document update failed", slog.String("details", err.Error()))
@@ -1,6 +1,7 @@
 package swagger
 
 import (
+	"slices"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -49,6 +50,15 @@ func WatchFile(filename string, debounceTime time.Duration) (*Watcher, error) {
 	return out, nil
 }
 
+// Add watches for updates of another file, files watched already are
+// ignored
+func (w *Watcher) Add(filename string) error {
+	if slices.Contains(w.watcher.WatchList(), filename) {
+		return nil
+	}
+	return w.watcher.Add(filename)
+}
+
 func (w *Watcher) debounceUpdate() {
 	if w.timer != nil {
 		w.timer.Stop()
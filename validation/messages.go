@@ -0,0 +1,393 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/masnyjimmy/qapi/docs"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var printer = message.NewPrinter(language.English)
+
+var schemaTypes = []string{"boolean", "string", "integer", "number"}
+
+// pattern of paths tree keys, as in schema.json
+const pathPattern = `^(?:(?:\/[a-zA-Z0-9$-_.+!*'()]+)|(?:\/{[a-zA-Z0-9]+}))+$`
+
+var pathExpr = regexp.MustCompile(pathPattern)
+
+// translator turns validation errors into diagnostics about qapi document
+type translator struct {
+	document any
+	out      docs.Diagnostics
+}
+
+// leaves collects failures of validation error tree, only the closest
+// alternative of oneOf / anyOf is followed
+func leaves(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	switch err.ErrorKind.(type) {
+	case *kind.OneOf, *kind.AnyOf:
+		return leaves(closestAlternative(err.Causes))
+	}
+
+	var out []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		out = append(out, leaves(cause)...)
+	}
+	return out
+}
+
+// closestAlternative prefers alternatives failing deeper in the document,
+// then the ones with matching type
+func closestAlternative(causes []*jsonschema.ValidationError) *jsonschema.ValidationError {
+	score := func(err *jsonschema.ValidationError) int {
+		depth := 0
+		typeMismatch := false
+		for _, leaf := range leaves(err) {
+			depth = max(depth, len(leaf.InstanceLocation))
+			if _, ok := leaf.ErrorKind.(*kind.Type); ok && len(leaf.InstanceLocation) == len(err.InstanceLocation) {
+				typeMismatch = true
+			}
+		}
+		if !typeMismatch {
+			depth++
+		}
+		return depth
+	}
+
+	best := causes[0]
+	bestScore := score(best)
+	for _, cause := range causes[1:] {
+		if s := score(cause); s > bestScore {
+			best, bestScore = cause, s
+		}
+	}
+	return best
+}
+
+// schemaAt resolves keyword location of validation error in qapi schema
+func schemaAt(schemaURL string) map[string]any {
+	_, fragment, _ := strings.Cut(schemaURL, "#")
+	fragment, _ = url.PathUnescape(fragment)
+
+	var node any = rawSchema
+	for _, segment := range strings.Split(fragment, "/") {
+		if segment == "" {
+			continue
+		}
+		segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+
+		switch n := node.(type) {
+		case map[string]any:
+			node = n[segment]
+		case []any:
+			var idx int
+			if _, err := fmt.Sscan(segment, &idx); err != nil || idx >= len(n) {
+				return nil
+			}
+			node = n[idx]
+		default:
+			return nil
+		}
+	}
+
+	out, _ := node.(map[string]any)
+	return out
+}
+
+// definition returns name of $defs entry of keyword location, with
+// remaining keyword path
+func definition(schemaURL string) (string, string) {
+	_, fragment, _ := strings.Cut(schemaURL, "#/$defs/")
+	name, rest, _ := strings.Cut(fragment, "/")
+	return name, rest
+}
+
+func (t *translator) instance(path []string) any {
+	var node any = t.document
+	for _, segment := range path {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = m[segment]
+	}
+	return node
+}
+
+// subject names node of document for messages, e.g. method `GetImage`,
+// errors of declared properties name the owning node
+func (t *translator) subject(err *jsonschema.ValidationError) string {
+	path := err.InstanceLocation
+	name, rest := definition(err.SchemaURL)
+
+	if _, ok := property(err); ok {
+		path, rest = path[:len(path)-1], ""
+	}
+
+	last := ""
+	if len(path) != 0 {
+		last = path[len(path)-1]
+	}
+
+	switch name {
+	case "Method":
+		if method, ok := t.instance(path).(map[string]any); ok {
+			if id, ok := method["id"].(string); ok && id != "" {
+				return fmt.Sprintf("method `%v`", id)
+			}
+		}
+		return fmt.Sprintf("method `%v %v`", last, urlPath(path))
+	case "Path":
+		return fmt.Sprintf("path `%v`", urlPath(path))
+	case "PathItem":
+		return fmt.Sprintf("path item `%v`", last)
+	case "Responses":
+		if rest != "" {
+			return fmt.Sprintf("response `%v`", responseCode(path))
+		}
+		return "responses"
+	case "Info":
+		return "info"
+	case "Server":
+		return "server"
+	case "Tag":
+		return "tag"
+	case "SecurityScheme":
+		return fmt.Sprintf("security scheme `%v`", last)
+	case "Traits":
+		if rest != "" {
+			return fmt.Sprintf("trait `%v`", traitName(path))
+		}
+		return "traits"
+	case "PathTraits":
+		return "path traits"
+	case "Links":
+		return fmt.Sprintf("link `%v`", last)
+	case "Params":
+		return "parameter"
+	case "DefaultResponsesPolicy":
+		return "default responses policy"
+	case "Schema", "TypedSchema":
+		return "schema"
+	}
+
+	if len(path) == 0 {
+		return "document"
+	}
+	return fmt.Sprintf("`%v`", last)
+}
+
+// urlPath joins path segments of paths tree
+func urlPath(path []string) string {
+	var sb strings.Builder
+	for _, segment := range path {
+		if strings.HasPrefix(segment, "/") {
+			sb.WriteString(segment)
+		}
+	}
+	return sb.String()
+}
+
+func responseCode(path []string) string {
+	if idx := slices.Index(path, "responses"); idx != -1 && idx+1 < len(path) {
+		return path[idx+1]
+	}
+	if len(path) >= 2 && (path[0] == "defaultResponses" || path[0] == "defaultResponseGroups") {
+		return path[len(path)-1]
+	}
+	return path[len(path)-1]
+}
+
+func traitName(path []string) string {
+	if len(path) >= 2 && path[0] == "traits" {
+		return path[1]
+	}
+	return path[len(path)-1]
+}
+
+// property returns key of node when error is located at declared property
+// of its parent, e.g. type of security scheme
+func property(err *jsonschema.ValidationError) (string, bool) {
+	_, rest := definition(err.SchemaURL)
+	if !strings.HasPrefix(rest, "properties/") || strings.Contains(strings.TrimPrefix(rest, "properties/"), "/") {
+		return "", false
+	}
+	return err.InstanceLocation[len(err.InstanceLocation)-1], true
+}
+
+// value describes invalid value, with its key when it's a declared property
+func (t *translator) value(err *jsonschema.ValidationError, got any) string {
+	if key, ok := property(err); ok {
+		return fmt.Sprintf("invalid value `%v` of `%v` in %v", got, key, t.subject(err))
+	}
+	return fmt.Sprintf("invalid value `%v` in %v", got, t.subject(err))
+}
+
+func (t *translator) add(path []string, format string, args ...any) {
+	t.out = append(t.out, docs.Diagnostic{
+		Severity: docs.SeverityError,
+		Path:     slices.Clone(docs.NodePath(path)),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (t *translator) translate(err *jsonschema.ValidationError) {
+	path := err.InstanceLocation
+
+	switch k := err.ErrorKind.(type) {
+	case *kind.AdditionalProperties:
+		keys := knownKeys(schemaAt(err.SchemaURL))
+		for _, property := range k.Properties {
+			msg := fmt.Sprintf("unknown key `%v` in %v", property, t.subject(err))
+			if suggestion, ok := suggestKey(property, schemaAt(err.SchemaURL), keys); ok {
+				msg += fmt.Sprintf(", did you mean `%v`?", suggestion)
+			}
+			t.add(append(slices.Clone(path), property), "%v", msg)
+		}
+	case *kind.Required:
+		for _, missing := range k.Missing {
+			t.add(path, "missing required key `%v` in %v", missing, t.subject(err))
+		}
+	case *kind.Enum:
+		wants := make([]string, len(k.Want))
+		for i, want := range k.Want {
+			wants[i] = fmt.Sprint(want)
+		}
+		msg := fmt.Sprintf("%v, expected one of: %v", t.value(err, k.Got), strings.Join(wants, ", "))
+		if suggestion, ok := suggest(fmt.Sprint(k.Got), wants); ok {
+			msg += fmt.Sprintf(", did you mean `%v`?", suggestion)
+		}
+		t.add(path, "%v", msg)
+	case *kind.Pattern:
+		if schemaAt(err.SchemaURL)["format"] == "schema-expression" {
+			msg := fmt.Sprintf("invalid schema expression `%v`", k.Got)
+			if suggestion, ok := suggestSchemaExpr(k.Got); ok {
+				msg += fmt.Sprintf(", did you mean `%v`?", suggestion)
+			}
+			t.add(path, "%v", msg)
+			return
+		}
+		t.add(path, "%v, it must match %v", t.value(err, k.Got), k.Want)
+	case *kind.Type:
+		if key, ok := property(err); ok {
+			t.add(path, "`%v` of %v must be %v, got %v", key, t.subject(err), strings.Join(k.Want, " or "), k.Got)
+			return
+		}
+		t.add(path, "%v must be %v, got %v", t.subject(err), strings.Join(k.Want, " or "), k.Got)
+	default:
+		t.add(path, "%v", err.ErrorKind.LocalizedString(printer))
+	}
+}
+
+// knownKeys lists keys declared by schema properties
+func knownKeys(schema map[string]any) []string {
+	properties, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// suggestKey finds declared key close to unknown one, e.g. method, keys
+// missing leading slash of nested paths are suggested only when no declared
+// key is close
+func suggestKey(key string, schema map[string]any, keys []string) (string, bool) {
+	if suggestion, ok := suggest(key, keys); ok {
+		return suggestion, true
+	}
+
+	patterns, _ := schema["patternProperties"].(map[string]any)
+	if _, isPath := patterns[pathPattern]; isPath && !strings.HasPrefix(key, "/") {
+		if slashed := "/" + key; pathExpr.MatchString(slashed) {
+			return slashed, true
+		}
+	}
+
+	return "", false
+}
+
+// suggestSchemaExpr corrects misspelled type of schema expression
+func suggestSchemaExpr(expr string) (string, bool) {
+	end := strings.IndexFunc(expr, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if end == -1 {
+		end = len(expr)
+	}
+
+	if end == 0 {
+		return "", false
+	}
+
+	typ, ok := suggest(expr[:end], schemaTypes)
+	if !ok {
+		return "", false
+	}
+	return typ + expr[end:], true
+}
+
+// suggest returns candidate closest to value, when it is close enough to
+// be a typo
+func suggest(value string, candidates []string) (string, bool) {
+	best, bestDistance := "", -1
+	lower := strings.ToLower(value)
+
+	for _, candidate := range candidates {
+		d := distance(lower, strings.ToLower(candidate))
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+
+	if bestDistance == -1 || bestDistance > max(1, min(len(value), len(best))/3) {
+		return "", false
+	}
+	return best, best != value
+}
+
+// distance is optimal string alignment distance of a and b, Levenshtein
+// distance counting transposition of adjacent characters as single edit,
+// e.g. gte and get
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
@@ -3,7 +3,9 @@ package validation
 import (
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 
 	"github.com/goccy/go-yaml"
 	"github.com/santhosh-tekuri/jsonschema/v6"
@@ -14,29 +16,74 @@ var schemaBytes []byte
 
 var schema *jsonschema.Schema
 
+// schema of imported files, without info and servers
+var fragmentSchema *jsonschema.Schema
+
+// schema.json decoded, used to explain validation errors
+var rawSchema any
+
 func init() {
 	compiler := jsonschema.NewCompiler()
 	compiler.DefaultDraft(jsonschema.Draft2020)
 
-	var object any
-
-	if err := json.Unmarshal(schemaBytes, &object); err != nil {
+	if err := json.Unmarshal(schemaBytes, &rawSchema); err != nil {
 		panic(err)
 	}
 
-	if err := compiler.AddResource("qapi-schema.json", object); err != nil {
+	if err := compiler.AddResource("qapi-schema.json", rawSchema); err != nil {
 		panic(err)
 	}
 
 	schema = compiler.MustCompile("qapi-schema.json")
+
+	fragment := maps.Clone(rawSchema.(map[string]any))
+	delete(fragment, "required")
+
+	properties := maps.Clone(fragment["properties"].(map[string]any))
+	delete(properties, "info")
+	delete(properties, "servers")
+	fragment["properties"] = properties
+
+	if err := compiler.AddResource("qapi-fragment.json", fragment); err != nil {
+		panic(err)
+	}
+
+	fragmentSchema = compiler.MustCompile("qapi-fragment.json")
 }
 
+// Validate checks document against qapi schema, validation failures are
+// returned as docs.Diagnostics located at the offending nodes
 func Validate(documentBytes []byte) error {
+	return validate(schema, documentBytes)
+}
+
+// ValidateFragment checks imported document, which can't define info and
+// servers of the api
+func ValidateFragment(documentBytes []byte) error {
+	return validate(fragmentSchema, documentBytes)
+}
+
+func validate(schema *jsonschema.Schema, documentBytes []byte) error {
 	var document any
 
 	if err := yaml.Unmarshal(documentBytes, &document); err != nil {
 		return fmt.Errorf("Unable to parse document: %w", err)
 	}
 
-	return schema.Validate(document)
+	err := schema.Validate(document)
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+
+	t := translator{
+		document: document,
+	}
+
+	for _, leaf := range leaves(validationErr) {
+		t.translate(leaf)
+	}
+
+	return t.out
 }